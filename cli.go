@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/app"
+	"github.com/jaycee1285/labwcchanger-tui/internal/config"
+	"github.com/jaycee1285/labwcchanger-tui/internal/daemon"
+	"github.com/jaycee1285/labwcchanger-tui/internal/history"
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// Every command except `daemon` goes through the daemon when one is
+// running, and falls back to doing the work in-process otherwise.
+func runCommand(name string, args []string) error {
+	switch name {
+	case "daemon":
+		return cmdDaemon()
+	case "list":
+		return cmdList(args)
+	case "current":
+		return cmdCurrent(args)
+	case "select":
+		return cmdSelect(args)
+	case "apply":
+		return cmdApply(args)
+	case "profile":
+		return cmdProfile(args)
+	case "history":
+		return cmdHistory(args)
+	case "generate-theme":
+		return cmdGenerateTheme(args)
+	case "rollback", "next", "previous", "pause", "resume":
+		_, err := daemon.Call(daemon.Request{Cmd: name})
+		return needsDaemon(err)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q (try help)", name)
+	}
+}
+
+const usage = `usage: labwcchanger-tui [command]
+
+With no command, starts the TUI.
+
+  daemon                     serve the IPC socket and run the schedule
+  list [category]            list items (style, gtk, icons, labwc, kitty, walls)
+  current [-json]            show the current selections
+  select <category> <name>   stage a selection in the daemon
+  apply [-gtk ..] [-icons ..] [-labwc ..] [-kitty ..] [-wallpaper ..]
+                             apply staged selections plus any flags
+  apply -from-history N      re-apply entry N of history (flags still override)
+  apply -dry-run ...         print what apply would change, change nothing
+  history [-n N]             list past applies, newest first
+  profile <name>             apply a profile from config.json
+  generate-theme [-name ..] <kitty theme>
+                             write an Openbox theme colored from a kitty theme
+  rollback                   re-apply the setup before the last apply
+  next | previous            rotate the wallpaper (daemon only)
+  pause | resume             stop/restart timed wallpaper rotation
+`
+
+func cmdDaemon() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := daemon.Run(ctx, cfg); err != nil && !errors.Is(err, context.Canceled) {
+		return err
+	}
+	return nil
+}
+
+func cmdList(args []string) error {
+	category := ""
+	if len(args) > 0 {
+		category = args[0]
+	}
+	var cat theme.Catalog
+	resp, err := daemon.Call(daemon.Request{Cmd: daemon.CmdList})
+	switch {
+	case err == nil:
+		cat = *resp.Catalog
+	case errors.Is(err, daemon.ErrNotRunning):
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		cat = theme.ScanCatalog(cfg.ScanOptions())
+	default:
+		return err
+	}
+
+	if category != "" {
+		items, ok := cat.Category(category)
+		if !ok {
+			return fmt.Errorf("unknown category %q", category)
+		}
+		fmt.Println(strings.Join(items, "\n"))
+		return nil
+	}
+	for _, c := range theme.Categories {
+		items, _ := cat.Category(c)
+		fmt.Printf("%s (%d)\n", c, len(items))
+		for _, it := range items {
+			fmt.Println("  " + it)
+		}
+	}
+	return nil
+}
+
+func cmdCurrent(args []string) error {
+	fs := flag.NewFlagSet("current", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var sel app.Selections
+	resp, err := daemon.Call(daemon.Request{Cmd: daemon.CmdCurrent})
+	switch {
+	case err == nil:
+		sel = *resp.Current
+	case errors.Is(err, daemon.ErrNotRunning):
+		cs := theme.LoadCurrentSettings()
+		sel = app.Selections{OpenboxTheme: cs.OpenboxTheme, GtkTheme: cs.GtkTheme, IconTheme: cs.IconTheme}
+	default:
+		return err
+	}
+
+	if *asJSON {
+		return json.NewEncoder(os.Stdout).Encode(sel)
+	}
+	fmt.Printf("gtk: %s\nicons: %s\nlabwc: %s\nkitty: %s\nwallpaper: %s\n",
+		sel.GtkTheme, sel.IconTheme, sel.OpenboxTheme, sel.KittyTheme, sel.Wallpaper)
+	return nil
+}
+
+func cmdSelect(args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: select <category> <name>")
+	}
+	_, err := daemon.Call(daemon.Request{Cmd: daemon.CmdSelect, Category: args[0], Name: args[1]})
+	return needsDaemon(err)
+}
+
+func cmdApply(args []string) error {
+	fs := flag.NewFlagSet("apply", flag.ContinueOnError)
+	var sel app.Selections
+	fs.StringVar(&sel.GtkTheme, "gtk", "", "GTK theme")
+	fs.StringVar(&sel.IconTheme, "icons", "", "icon theme")
+	fs.StringVar(&sel.OpenboxTheme, "labwc", "", "LabWC/Openbox theme")
+	fs.StringVar(&sel.KittyTheme, "kitty", "", "kitty theme")
+	fs.StringVar(&sel.Wallpaper, "wallpaper", "", "wallpaper file name")
+	fromHistory := fs.Int("from-history", 0, "re-apply history entry `N` (1 = most recent)")
+	dryRun := fs.Bool("dry-run", false, "show what would change without changing it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	style := ""
+	if *fromHistory != 0 {
+		e, err := history.Nth(*fromHistory)
+		if err != nil {
+			return err
+		}
+		sel = e.Selections.Overlay(sel)
+		style = e.Style
+	} else if resp, err := daemon.Call(daemon.Request{Cmd: daemon.CmdCurrent}); err == nil {
+		// The daemon applies what it's sent as is; flags go on top of
+		// what's staged there.
+		sel = resp.Pending.Overlay(sel)
+	}
+	if sel.Empty() {
+		return errors.New("nothing selected; usage: apply [-gtk ..] [-icons ..] [-labwc ..] [-kitty ..] [-wallpaper ..]")
+	}
+
+	resp, err := daemon.Call(daemon.Request{Cmd: daemon.CmdApply, Selections: &sel, Style: style, DryRun: *dryRun})
+	if err == nil {
+		printReport(resp.Report)
+		return nil
+	}
+	if !errors.Is(err, daemon.ErrNotRunning) {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	cfg.DryRun = *dryRun
+	rep, err := history.Apply(sel, style, cfg.Options)
+	printReport(&rep)
+	return err
+}
+
+func cmdProfile(args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: profile <name>")
+	}
+	_, err := daemon.Call(daemon.Request{Cmd: daemon.CmdProfile, Name: args[0]})
+	if !errors.Is(err, daemon.ErrNotRunning) {
+		return err
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	p, ok := cfg.Profiles[args[0]]
+	if !ok {
+		return fmt.Errorf("unknown profile %q", args[0])
+	}
+	rep, err := history.Apply(cfg.Resolve(p, theme.ScanCatalog(cfg.ScanOptions())), p.Style, cfg.Options)
+	printReport(&rep)
+	return err
+}
+
+func cmdHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	n := fs.Int("n", 20, "show at most `N` entries")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	entries, err := history.Load()
+	if err != nil {
+		return err
+	}
+	for i := 1; i <= len(entries) && i <= *n; i++ {
+		e := entries[len(entries)-i]
+		fmt.Printf("%3d  %s  %s\n", i, e.Time.Local().Format("2006-01-02 15:04"), e.Summary())
+	}
+	return nil
+}
+
+func cmdGenerateTheme(args []string) error {
+	fs := flag.NewFlagSet("generate-theme", flag.ContinueOnError)
+	name := fs.String("name", "", "theme `name` (default <kitty theme>-palette)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: generate-theme [-name NAME] <kitty theme>")
+	}
+	kitty := fs.Arg(0)
+	if *name == "" {
+		*name = theme.PaletteThemeName(kitty)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	var rep app.Report
+	dir, err := app.GenerateOpenboxTheme(*name, kitty, cfg.Options, &rep)
+	printReport(&rep)
+	if err != nil {
+		return err
+	}
+	fmt.Println(*name, "in", dir)
+	return nil
+}
+
+// printReport shows the warnings of an apply, and for a dry run the
+// actions too; otherwise what went right is left to the history file.
+func printReport(rep *app.Report) {
+	if rep == nil {
+		return
+	}
+	if rep.DryRun {
+		fmt.Println("dry run, nothing changed; apply would:")
+		for _, a := range rep.Actions {
+			fmt.Println("  " + a)
+		}
+	}
+	for _, w := range rep.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", w)
+	}
+}
+
+func needsDaemon(err error) error {
+	if errors.Is(err, daemon.ErrNotRunning) {
+		return errors.New("this command needs a running daemon (labwcchanger-tui daemon)")
+	}
+	return err
+}
@@ -8,49 +8,315 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
-	"github.com/beevik/etree"
 	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
 )
 
 type Selections struct {
-	OpenboxTheme string
-	GtkTheme     string
-	IconTheme    string
-	KittyTheme   string
-	Wallpaper    string
+	OpenboxTheme string `json:"openbox,omitempty"`
+	GtkTheme     string `json:"gtk,omitempty"`
+	IconTheme    string `json:"icons,omitempty"`
+	KittyTheme   string `json:"kitty,omitempty"`
+	Wallpaper    string `json:"wallpaper,omitempty"`
+	// Wallpapers overrides Wallpaper for individual outputs, by output name.
+	Wallpapers map[string]string `json:"wallpapers,omitempty"`
+	// Labwc holds the other rc.xml <theme> settings.
+	Labwc *LabwcOptions `json:"labwc_options,omitempty"`
 }
 
-func Apply(sel Selections) error {
-	if err := updateRcXml(sel); err != nil {
-		return err
+// Set stores value under a CLI/daemon category name; "walls:DP-1" targets
+// one output. Styles aren't handled here since they need the catalog to expand.
+func (s *Selections) Set(category, value string) bool {
+	if base, output, ok := strings.Cut(category, ":"); ok && isWallCategory(base) && output != "" {
+		s.SetOutputWallpaper(output, value)
+		return true
 	}
-	if err := updateGSettings(sel); err != nil {
-		return err
+	switch category {
+	case "gtk":
+		s.GtkTheme = value
+	case "icons", "icon":
+		s.IconTheme = value
+	case "labwc", "openbox":
+		s.OpenboxTheme = value
+	case "kitty":
+		s.KittyTheme = value
+	case "walls", "wallpaper", "wallpapers":
+		s.Wallpaper = value
+	default:
+		return false
 	}
-	if err := updateEnvironment(sel); err != nil {
-		return err
+	return true
+}
+
+func isWallCategory(c string) bool {
+	return c == "walls" || c == "wallpaper" || c == "wallpapers"
+}
+
+// SetOutputWallpaper assigns a wallpaper to one output; "" clears it so the
+// output falls back to Wallpaper.
+func (s *Selections) SetOutputWallpaper(output, wall string) {
+	m := make(map[string]string, len(s.Wallpapers)+1)
+	for k, v := range s.Wallpapers {
+		m[k] = v
 	}
-	if sel.Wallpaper != "" {
-		wpPath := filepath.Join(theme.WallpaperDir(), sel.Wallpaper)
-		_ = run("swww", "img", wpPath)
+	if wall == "" {
+		delete(m, output)
+	} else {
+		m[output] = wall
+	}
+	if len(m) == 0 {
+		m = nil
+	}
+	s.Wallpapers = m
+}
+
+// WallpaperFor returns the wallpaper an output ends up with.
+func (s Selections) WallpaperFor(output string) string {
+	if w := s.Wallpapers[output]; w != "" {
+		return w
+	}
+	return s.Wallpaper
+}
+
+// Empty reports whether s selects nothing at all.
+func (s Selections) Empty() bool {
+	return s.OpenboxTheme == "" && s.GtkTheme == "" && s.IconTheme == "" && s.KittyTheme == "" &&
+		s.Wallpaper == "" && len(s.Wallpapers) == 0 && s.Labwc == nil
+}
+
+// Overlay returns s with every non-empty field of o copied over it.
+// A non-nil Wallpapers replaces s.Wallpapers whole, so outputs o cleared
+// stay cleared.
+func (s Selections) Overlay(o Selections) Selections {
+	if o.OpenboxTheme != "" {
+		s.OpenboxTheme = o.OpenboxTheme
+	}
+	if o.GtkTheme != "" {
+		s.GtkTheme = o.GtkTheme
 	}
-	if sel.KittyTheme != "" {
-		if err := applyKittyTheme(sel.KittyTheme); err != nil {
-			return err
+	if o.IconTheme != "" {
+		s.IconTheme = o.IconTheme
+	}
+	if o.KittyTheme != "" {
+		s.KittyTheme = o.KittyTheme
+	}
+	if o.Wallpaper != "" {
+		s.Wallpaper = o.Wallpaper
+	}
+	if o.Wallpapers != nil {
+		s.Wallpapers = nil
+		for output, w := range o.Wallpapers {
+			s.SetOutputWallpaper(output, w)
 		}
-		if err := updateFuzzelColors(sel.KittyTheme); err != nil {
-			return err
+	}
+	if o.Labwc != nil {
+		var merged LabwcOptions
+		if s.Labwc != nil {
+			merged = *s.Labwc
 		}
+		merged = merged.Overlay(*o.Labwc)
+		s.Labwc = &merged
 	}
-	_ = run("labwc", "-r")
+	return s
+}
+
+// Options are the config.json settings that change how Apply works.
+type Options struct {
+	Wallpaper WallpaperOptions `json:"wallpaper,omitempty"`
+	// CreateMissing lets Apply create rc.xml and the labwc environment
+	// file, or the entries missing from them, instead of skipping.
+	CreateMissing bool `json:"create_missing,omitempty"`
+	// ThemercOverride colors labwc's window decorations from the kitty
+	// palette via themerc-override.
+	ThemercOverride bool `json:"themerc_override,omitempty"`
+	// GtkCssOverride adds libadwaita color overrides from the kitty
+	// palette to the user's gtk.css.
+	GtkCssOverride bool `json:"gtk_css_override,omitempty"`
+	// LinkGtk4 links the GTK theme's gtk-4.0 files into ~/.config/gtk-4.0
+	// so libadwaita apps follow it.
+	LinkGtk4 bool `json:"link_gtk4,omitempty"`
+	// FlatpakOverrides exposes themes to Flatpak apps through the global
+	// overrides file.
+	FlatpakOverrides bool `json:"flatpak_overrides,omitempty"`
+	// PaletteOpenbox gives styles that match no installed Openbox theme
+	// one generated from their kitty theme's palette.
+	PaletteOpenbox bool `json:"palette_openbox,omitempty"`
+	// KittyDirect sets the kitty theme without `kitten themes`, for themes
+	// that aren't in kitty's registry.
+	KittyDirect bool `json:"kitty_direct,omitempty"`
+	// Fuzzel maps the kitty palette onto fuzzel's colors.
+	Fuzzel FuzzelOptions `json:"fuzzel,omitempty"`
+	// Rofi, Wofi and Tofi recolor those launchers like fuzzel.
+	Rofi bool `json:"rofi,omitempty"`
+	Wofi bool `json:"wofi,omitempty"`
+	Tofi bool `json:"tofi,omitempty"`
+	// Contrast is checked on every generated text color.
+	Contrast ContrastOptions `json:"contrast,omitempty"`
+	// DryRun makes Apply report what it would do and change nothing.
+	DryRun bool `json:"-"`
+}
+
+// Report says what an Apply did, for the status line and history.
+type Report struct {
+	DryRun   bool     `json:"dry_run,omitempty"`
+	Actions  []string `json:"actions,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (r *Report) did(format string, args ...any) {
+	r.Actions = append(r.Actions, fmt.Sprintf(format, args...))
+}
+
+func (r *Report) warn(format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// A step is one stage of Apply. categories are the catalog categories
+// whose selection it acts on; Preview runs the steps of one category that
+// take effect immediately and are cheap enough to repeat while scrolling.
+type step struct {
+	categories []string
+	preview    bool
+	run        func(sel Selections, opts Options, rep *Report) error
+}
+
+// steps is Apply, in order.
+var steps = []step{
+	{[]string{"labwc"}, false, paletteThemeStep},
+	{[]string{"labwc", "icons"}, true, updateRcXml},
+	{[]string{"gtk", "icons"}, true, updateGSettings},
+	{[]string{"gtk"}, false, updateEnvironment},
+	{[]string{"gtk"}, false, gtk4LinkStep},
+	{[]string{"gtk", "icons"}, false, flatpakStep},
+	{[]string{"walls"}, true, wallpaperStep},
+	{[]string{"kitty"}, true, kittyStep},
+	{[]string{"kitty"}, true, themercStep},
+	{[]string{"kitty"}, true, launchersStep},
+	{[]string{"kitty"}, false, gtkCssStep},
+	{[]string{"labwc", "icons", "kitty"}, true, reloadLabwc},
+	{[]string{"gtk", "icons"}, false, restartWaybar},
+}
 
-	// Waybar doesn't always pick up GTK theme changes unless restarted.
+func (s step) handles(category string) bool {
+	for _, c := range s.categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+func Apply(sel Selections, opts Options) (Report, error) {
+	rep := Report{DryRun: opts.DryRun}
+	for _, st := range steps {
+		if err := st.run(sel, opts, &rep); err != nil {
+			return rep, err
+		}
+	}
+	return rep, nil
+}
+
+func wallpaperStep(sel Selections, opts Options, rep *Report) error {
+	if opts.DryRun {
+		walls := wallpaperPaths(sel)
+		if p, ok := walls[""]; ok {
+			rep.did("wallpaper %s", p)
+		}
+		for _, o := range walls.outputs() {
+			rep.did("wallpaper on %s: %s", o, walls[o])
+		}
+		return nil
+	}
+	if err := SetWallpaper(sel, opts); err != nil {
+		// A missing or broken wallpaper tool shouldn't undo the themes.
+		rep.warn("wallpaper: %v", err)
+		return nil
+	}
+	if n := len(wallpaperPaths(sel)); n > 0 {
+		rep.did("wallpaper set (%d target(s))", n)
+	}
+	return nil
+}
+
+func kittyStep(sel Selections, opts Options, rep *Report) error {
+	if sel.KittyTheme == "" {
+		return nil
+	}
+	mode := ""
+	if opts.KittyDirect {
+		mode = " (direct)"
+	}
+	if p, err := resolveKittyThemeFile(sel.KittyTheme); err == nil {
+		if errs := theme.LoadKittyColors(p).Errors; len(errs) > 0 {
+			rep.warn("kitty theme %s has %d problem(s), first: %v", sel.KittyTheme, len(errs), errs[0])
+		}
+	}
+	if opts.DryRun {
+		rep.did("kitty theme %s%s", sel.KittyTheme, mode)
+		rep.did("fuzzel colors from %s", sel.KittyTheme)
+		return nil
+	}
+	apply := func() error { return applyKittyTheme(sel.KittyTheme) }
+	if opts.KittyDirect {
+		apply = func() error { return applyKittyDirect(sel.KittyTheme, rep) }
+	}
+	if err := apply(); err != nil {
+		return err
+	}
+	rep.did("kitty theme %s%s", sel.KittyTheme, mode)
+	if err := updateFuzzelColors(sel.KittyTheme, opts, rep); err != nil {
+		return err
+	}
+	rep.did("fuzzel colors from %s", sel.KittyTheme)
+	return nil
+}
+
+func reloadLabwc(_ Selections, opts Options, rep *Report) error {
+	if opts.DryRun {
+		rep.did("labwc -r")
+		return nil
+	}
+	if err := run("labwc", "-r"); err != nil {
+		rep.warn("labwc -r failed (not running under labwc?)")
+	}
+	return nil
+}
+
+// Waybar doesn't always pick up GTK theme changes unless restarted.
+func restartWaybar(_ Selections, opts Options, rep *Report) error {
+	if opts.DryRun {
+		rep.did("restart waybar")
+		return nil
+	}
 	_ = runNoFail("pkill", "waybar")
 	_ = startNoWait("waybar")
 	return nil
 }
 
+// SetWallpaper is the wallpaper step of Apply on its own, for rotation:
+// no labwc reload, no waybar restart.
+func SetWallpaper(sel Selections, opts Options) error {
+	walls := wallpaperPaths(sel)
+	if len(walls) == 0 {
+		return nil
+	}
+	return setWallpapers(walls, opts.Wallpaper)
+}
+
+func wallpaperPaths(sel Selections) OutputWallpapers {
+	walls := OutputWallpapers{}
+	if sel.Wallpaper != "" {
+		walls[""] = filepath.Join(theme.WallpaperDir(), sel.Wallpaper)
+	}
+	for output, w := range sel.Wallpapers {
+		if w != "" {
+			walls[output] = filepath.Join(theme.WallpaperDir(), w)
+		}
+	}
+	return walls
+}
+
 func runNoFail(name string, args ...string) error {
 	cmd := exec.Command(name, args...)
 	cmd.Stdout = nil
@@ -70,53 +336,127 @@ func startNoWait(name string, args ...string) error {
 	return nil
 }
 
-func updateRcXml(sel Selections) error {
+// updateRcXml edits only the values that change (see rcDoc), so the rest
+// of a hand-written rc.xml stays as it was.
+func updateRcXml(sel Selections, opts Options, rep *Report) error {
+	if sel.OpenboxTheme == "" && sel.IconTheme == "" && sel.Labwc == nil {
+		return nil
+	}
+	if sel.Labwc != nil {
+		if err := sel.Labwc.Validate(); err != nil {
+			return fmt.Errorf("labwc options: %w", err)
+		}
+	}
 	rc := theme.LabwcRcPath()
-	if _, err := os.Stat(rc); err != nil {
-		return nil // match Flutter: do nothing if missing
+	src, err := os.ReadFile(rc)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read rc.xml: %w", err)
 	}
-	doc := etree.NewDocument()
-	if err := doc.ReadFromFile(rc); err != nil {
+	if err != nil {
+		if !opts.CreateMissing {
+			rep.warn("%s missing, LabWC theme not set", rc)
+			return nil // match Flutter: do nothing if missing
+		}
+		src = []byte(newRcXml)
+		rep.did("create %s", rc)
+	}
+	doc, err := editRcXml(src, sel, opts, rep)
+	if err != nil {
 		return fmt.Errorf("read rc.xml: %w", err)
 	}
+	if !doc.dirty || opts.DryRun {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(rc), 0o755); err != nil {
+		return fmt.Errorf("write rc.xml: %w", err)
+	}
+	if err := os.WriteFile(rc, doc.bytes(), 0o644); err != nil {
+		return fmt.Errorf("write rc.xml: %w", err)
+	}
+	return nil
+}
+
+// editRcXml makes the selection's edits to an rc.xml read from src.
+func editRcXml(src []byte, sel Selections, opts Options, rep *Report) (*rcDoc, error) {
+	doc, err := parseRc(src)
+	if err != nil {
+		return nil, err
+	}
 
-	if sel.OpenboxTheme != "" {
-		for _, el := range doc.FindElements("//theme/name") {
-			if el.Parent() != nil && el.Parent().Tag == "theme" {
-				el.SetText(sel.OpenboxTheme)
-				break
+	set := func(tag, value string) {
+		if value == "" {
+			return
+		}
+		el := doc.find("theme", tag)
+		if el == nil {
+			if !opts.CreateMissing {
+				rep.warn("rc.xml has no <theme><%s>, not set", tag)
+				return
 			}
+			el = createRcThemeChild(doc, tag, rep)
+		}
+		if doc.text(el) != value {
+			doc.setText(el, value)
+			rep.did("rc.xml <theme><%s> %s", tag, value)
 		}
 	}
-	if sel.IconTheme != "" {
-		for _, el := range doc.FindElements("//theme/icon") {
-			if el.Parent() != nil && el.Parent().Tag == "theme" {
-				el.SetText(sel.IconTheme)
-				break
-			}
+	set("name", sel.OpenboxTheme)
+	set("icon", sel.IconTheme)
+	if sel.Labwc != nil && !sel.Labwc.IsZero() {
+		th := rcTheme(doc)
+		if th == nil {
+			th = createRcTheme(doc, rep)
 		}
+		writeLabwcOptions(doc, th, *sel.Labwc, rep)
 	}
-	doc.Indent(2)
-	if err := doc.WriteToFile(rc); err != nil {
-		return fmt.Errorf("write rc.xml: %w", err)
+	return doc, nil
+}
+
+// rcTheme finds the <theme> element that holds the theme name, or the
+// top-level one.
+func rcTheme(doc *rcDoc) *rcNode {
+	if name := doc.find("theme", "name"); name != nil {
+		return name.parent
 	}
-	return nil
+	return doc.root.child("theme")
+}
+
+func createRcTheme(doc *rcDoc, rep *Report) *rcNode {
+	rep.did("rc.xml add <theme>")
+	return doc.add(doc.root, "theme")
+}
+
+// createRcThemeChild adds <tag> to <theme>, creating that as needed.
+func createRcThemeChild(doc *rcDoc, tag string, rep *Report) *rcNode {
+	th := rcTheme(doc)
+	if th == nil {
+		th = createRcTheme(doc, rep)
+	}
+	rep.did("rc.xml add <theme><%s>", tag)
+	return doc.add(th, tag)
 }
 
-func updateGSettings(sel Selections) error {
+func updateGSettings(sel Selections, opts Options, rep *Report) error {
 	if sel.GtkTheme != "" {
-		if err := run("gsettings", "set", "org.gnome.desktop.interface", "gtk-theme", sel.GtkTheme); err != nil {
-			return err
-		}
-		// Also update GTK-4.0 settings.ini
-		if err := updateGtk4Settings(sel.GtkTheme); err != nil {
-			// Don't fail if GTK-4.0 update fails, just continue
-			_ = err
+		rep.did("gtk-theme %s", sel.GtkTheme)
+		if !opts.DryRun {
+			if err := run("gsettings", "set", "org.gnome.desktop.interface", "gtk-theme", sel.GtkTheme); err != nil {
+				return err
+			}
+			// Also update GTK-4.0 settings.ini
+			if err := updateGtk4Settings(sel.GtkTheme); err != nil {
+				// Don't fail if GTK-4.0 update fails, just continue
+				rep.warn("gtk-4.0 settings.ini: %v", err)
+			}
 		}
 	}
 	if sel.IconTheme != "" {
-		if err := run("gsettings", "set", "org.gnome.desktop.interface", "icon-theme", sel.IconTheme); err != nil {
-			return err
+		rep.did("icon-theme %s", sel.IconTheme)
+		if !opts.DryRun {
+			if err := run("gsettings", "set", "org.gnome.desktop.interface", "icon-theme", sel.IconTheme); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
@@ -163,24 +503,32 @@ func updateGtk4Settings(themeName string) error {
 	return nil
 }
 
-func updateEnvironment(sel Selections) error {
+func updateEnvironment(sel Selections, opts Options, rep *Report) error {
 	if sel.GtkTheme == "" {
 		return nil
 	}
 	envPath := theme.LabwcEnvPath()
-	f, err := os.Open(envPath)
+	data, err := os.ReadFile(envPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read environment: %w", err)
+	}
 	if err != nil {
-		return nil // match Flutter: do nothing if missing
+		if !opts.CreateMissing {
+			rep.warn("%s missing, GTK_THEME not set", envPath)
+			return nil // match Flutter: do nothing if missing
+		}
+		rep.did("create %s", envPath)
 	}
-	defer f.Close()
 
 	var out bytes.Buffer
-	s := bufio.NewScanner(f)
+	found := false
+	s := bufio.NewScanner(bytes.NewReader(data))
 	for s.Scan() {
 		line := s.Text()
 		if len(line) >= 10 && line[:10] == "GTK_THEME=" {
 			out.WriteString("GTK_THEME=" + sel.GtkTheme)
 			out.WriteByte('\n')
+			found = true
 		} else {
 			out.WriteString(line)
 			out.WriteByte('\n')
@@ -189,6 +537,23 @@ func updateEnvironment(sel Selections) error {
 	if err := s.Err(); err != nil {
 		return fmt.Errorf("read environment: %w", err)
 	}
+	if !found {
+		if !opts.CreateMissing {
+			rep.warn("%s has no GTK_THEME= line, not set", envPath)
+			return nil
+		}
+		out.WriteString("GTK_THEME=" + sel.GtkTheme + "\n")
+	}
+	if bytes.Equal(out.Bytes(), data) {
+		return nil
+	}
+	rep.did("labwc environment GTK_THEME=%s", sel.GtkTheme)
+	if opts.DryRun {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(envPath), 0o755); err != nil {
+		return fmt.Errorf("write environment: %w", err)
+	}
 	if err := os.WriteFile(envPath, out.Bytes(), 0o644); err != nil {
 		return fmt.Errorf("write environment: %w", err)
 	}
@@ -0,0 +1,143 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelectionsSet(t *testing.T) {
+	tests := []struct {
+		category, value string
+		want            Selections
+		ok              bool
+	}{
+		{"gtk", "Adwaita", Selections{GtkTheme: "Adwaita"}, true},
+		{"icon", "Papirus", Selections{IconTheme: "Papirus"}, true},
+		{"openbox", "Nightmare", Selections{OpenboxTheme: "Nightmare"}, true},
+		{"kitty", "Nord", Selections{KittyTheme: "Nord"}, true},
+		{"wallpaper", "a.png", Selections{Wallpaper: "a.png"}, true},
+		{"walls:DP-1", "b.png", Selections{Wallpapers: map[string]string{"DP-1": "b.png"}}, true},
+		{"wallpapers:HDMI-A-1", "c.png", Selections{Wallpapers: map[string]string{"HDMI-A-1": "c.png"}}, true},
+		{"walls:", "d.png", Selections{}, false},
+		{"gtk:DP-1", "Adwaita", Selections{}, false},
+		{"style", "Nord", Selections{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			var s Selections
+			if ok := s.Set(tt.category, tt.value); ok != tt.ok {
+				t.Fatalf("Set ok = %v, want %v", ok, tt.ok)
+			}
+			if !reflect.DeepEqual(s, tt.want) {
+				t.Errorf("got %+v, want %+v", s, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectionsEmpty(t *testing.T) {
+	for _, tt := range []struct {
+		s    Selections
+		want bool
+	}{
+		{Selections{}, true},
+		{Selections{Wallpapers: map[string]string{}}, true},
+		{Selections{GtkTheme: "Adwaita"}, false},
+		{Selections{Wallpapers: map[string]string{"DP-1": "a.png"}}, false},
+		{Selections{Labwc: &LabwcOptions{}}, false},
+	} {
+		if got := tt.s.Empty(); got != tt.want {
+			t.Errorf("%+v.Empty() = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestSetOutputWallpaper(t *testing.T) {
+	orig := map[string]string{"DP-1": "a.png"}
+	s := Selections{Wallpapers: orig}
+	s.SetOutputWallpaper("HDMI-A-1", "b.png")
+	if want := map[string]string{"DP-1": "a.png", "HDMI-A-1": "b.png"}; !reflect.DeepEqual(s.Wallpapers, want) {
+		t.Errorf("after set: %v, want %v", s.Wallpapers, want)
+	}
+	if len(orig) != 1 {
+		t.Errorf("the original map was modified: %v", orig)
+	}
+
+	s.SetOutputWallpaper("DP-1", "")
+	if want := map[string]string{"HDMI-A-1": "b.png"}; !reflect.DeepEqual(s.Wallpapers, want) {
+		t.Errorf("after clear: %v, want %v", s.Wallpapers, want)
+	}
+	s.SetOutputWallpaper("HDMI-A-1", "")
+	if s.Wallpapers != nil {
+		t.Errorf("clearing the last output left %v, want nil", s.Wallpapers)
+	}
+}
+
+func TestWallpaperFor(t *testing.T) {
+	s := Selections{Wallpaper: "all.png", Wallpapers: map[string]string{"DP-1": "dp.png", "DP-2": ""}}
+	for output, want := range map[string]string{
+		"DP-1":     "dp.png",
+		"DP-2":     "all.png",
+		"HDMI-A-1": "all.png",
+		"":         "all.png",
+	} {
+		if got := s.WallpaperFor(output); got != want {
+			t.Errorf("WallpaperFor(%q) = %q, want %q", output, got, want)
+		}
+	}
+	if got := (Selections{}).WallpaperFor("DP-1"); got != "" {
+		t.Errorf("no wallpaper: got %q", got)
+	}
+}
+
+func TestSelectionsOverlay(t *testing.T) {
+	radius, shadows := 8, true
+	base := Selections{
+		OpenboxTheme: "Nightmare",
+		GtkTheme:     "Adwaita",
+		Wallpaper:    "all.png",
+		Wallpapers:   map[string]string{"DP-1": "a.png", "DP-2": "b.png"},
+		Labwc:        &LabwcOptions{CornerRadius: &radius},
+	}
+	tests := []struct {
+		name string
+		o    Selections
+		want Selections
+	}{
+		{"empty keeps everything", Selections{}, base},
+		{
+			"fields replace",
+			Selections{GtkTheme: "Orchis", KittyTheme: "Nord"},
+			Selections{
+				OpenboxTheme: "Nightmare", GtkTheme: "Orchis", KittyTheme: "Nord",
+				Wallpaper: "all.png", Wallpapers: base.Wallpapers, Labwc: base.Labwc,
+			},
+		},
+		{
+			"wallpapers replace whole",
+			Selections{Wallpapers: map[string]string{"DP-2": "c.png"}},
+			Selections{
+				OpenboxTheme: "Nightmare", GtkTheme: "Adwaita", Wallpaper: "all.png",
+				Wallpapers: map[string]string{"DP-2": "c.png"}, Labwc: base.Labwc,
+			},
+		},
+		{
+			"labwc options merge",
+			Selections{Labwc: &LabwcOptions{DropShadows: &shadows}},
+			Selections{
+				OpenboxTheme: "Nightmare", GtkTheme: "Adwaita", Wallpaper: "all.png",
+				Wallpapers: base.Wallpapers, Labwc: &LabwcOptions{CornerRadius: &radius, DropShadows: &shadows},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := base.Overlay(tt.o); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	if len(base.Wallpapers) != 2 || *base.Labwc.CornerRadius != 8 || base.Labwc.DropShadows != nil {
+		t.Errorf("Overlay modified its receiver: %+v", base)
+	}
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Generated colors live in a marked block of the user's gtk.css, wofi
+// style.css or rofi config.rasi so the rest of the file is theirs; each
+// Apply replaces or removes just the block.
+const (
+	colorBlockBegin = "/* labwcchanger-tui: begin generated colors, replaced on every apply */"
+	colorBlockEnd   = "/* labwcchanger-tui: end */"
+)
+
+// spliceColorBlock puts block in place of the generated block of a CSS-like
+// file (gtk.css, wofi's style.css, rofi's config.rasi), or removes it when
+// block is empty. A new block goes at the end, or at the top with atTop.
+func spliceColorBlock(path, block string, atTop bool, opts Options, rep *Report) error {
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+		// Writing would edit the linked theme's own file.
+		if block != "" {
+			rep.warn("%s is a link, color overrides not written", path)
+		}
+		return nil
+	}
+	old, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	content, had := cutColorBlock(string(old))
+	switch {
+	case block != "" && had:
+		// Replace in place, wherever the user moved it.
+		content = strings.Replace(string(old), colorBlockOf(string(old)), block, 1)
+	case block != "" && atTop:
+		if content != "" {
+			content = block + "\n" + content
+		} else {
+			content = block
+		}
+	case block != "":
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		if content != "" {
+			content += "\n"
+		}
+		content += block
+	case !had:
+		return nil
+	}
+	if content == string(old) {
+		return nil
+	}
+
+	remove := block == "" && strings.TrimSpace(content) == ""
+	switch {
+	case remove:
+		rep.did("remove %s", path)
+	case block == "":
+		rep.did("remove color overrides from %s", path)
+	default:
+		rep.did("color overrides in %s", path)
+	}
+	if opts.DryRun {
+		return nil
+	}
+	if remove {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove %s: %w", path, err)
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+// colorBlockOf returns the generated block in s, markers and trailing
+// newline included, or "".
+func colorBlockOf(s string) string {
+	i := strings.Index(s, colorBlockBegin)
+	if i < 0 {
+		return ""
+	}
+	j := strings.Index(s[i:], colorBlockEnd)
+	if j < 0 {
+		return s[i:]
+	}
+	end := i + j + len(colorBlockEnd)
+	if end < len(s) && s[end] == '\n' {
+		end++
+	}
+	return s[i:end]
+}
+
+// cutColorBlock returns s without the generated block, and whether it had one.
+func cutColorBlock(s string) (string, bool) {
+	b := colorBlockOf(s)
+	if b == "" {
+		return s, false
+	}
+	out := strings.Replace(s, b, "", 1)
+	// Drop the blank line added in front of an appended block, or after
+	// one at the top.
+	if strings.HasSuffix(out, "\n\n") && strings.HasSuffix(s, b) {
+		out = out[:len(out)-1]
+	}
+	if strings.HasPrefix(s, b) {
+		out = strings.TrimPrefix(out, "\n")
+	}
+	return out, true
+}
@@ -0,0 +1,265 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// ContrastOptions set the WCAG contrast every generated text/background
+// pair is checked against.
+type ContrastOptions struct {
+	// MinRatio defaults to 4.5, WCAG AA for normal text.
+	MinRatio float64 `json:"min_ratio,omitempty"`
+	// AutoAdjust moves a failing text color's lightness until it passes.
+	AutoAdjust bool `json:"auto_adjust,omitempty"`
+}
+
+const defaultMinContrast = 4.5
+
+func (o ContrastOptions) min() float64 {
+	if o.MinRatio <= 0 {
+		return defaultMinContrast
+	}
+	return o.MinRatio
+}
+
+func (o ContrastOptions) Validate() error {
+	// 0 means the default; ratios run from 1 (no contrast) to 21.
+	if o.MinRatio != 0 && (o.MinRatio < 1 || o.MinRatio > 21) {
+		return fmt.Errorf("contrast min_ratio %v: must be 1-21", o.MinRatio)
+	}
+	return nil
+}
+
+// ContrastCheck is one text color checked against its background.
+type ContrastCheck struct {
+	Target   string  // "fuzzel", "decorations"
+	Fg, Bg   string  // role names
+	FgColor  string  // #rrggbb as generated
+	BgColor  string  // #rrggbb
+	Ratio    float64 // of FgColor on BgColor
+	Min      float64
+	Adjusted string  // the text color written instead, "" if unchanged
+	NewRatio float64 // of Adjusted
+}
+
+func (c ContrastCheck) OK() bool { return c.Ratio >= c.Min }
+
+func (c ContrastCheck) String() string {
+	s := fmt.Sprintf("%s: %s on %s %.1f:1", c.Target, c.Fg, c.Bg, c.Ratio)
+	switch {
+	case c.Adjusted != "":
+		s += fmt.Sprintf(", adjusted to %s (%.1f:1)", c.Adjusted, c.NewRatio)
+	case !c.OK():
+		s += fmt.Sprintf(", below %.1f:1", c.Min)
+	}
+	return s
+}
+
+// contrastChecker checks the pairs of one generated file. fix returns
+// the text color to write and keeps the result for the report.
+type contrastChecker struct {
+	opts   ContrastOptions
+	target string
+	checks []ContrastCheck
+}
+
+func newContrastChecker(target string, opts ContrastOptions) *contrastChecker {
+	return &contrastChecker{opts: opts, target: target}
+}
+
+// fix takes colors as #rrggbb or rrggbb (an alpha suffix is kept but not
+// counted) and returns fg in the same form.
+func (c *contrastChecker) fix(fgName, fg, bgName, bg string) string {
+	if c == nil {
+		return fg
+	}
+	f, ok1 := parseHexColor(fg)
+	b, ok2 := parseHexColor(bg)
+	if !ok1 || !ok2 {
+		return fg
+	}
+	chk := ContrastCheck{
+		Target: c.target, Fg: fgName, Bg: bgName,
+		FgColor: f.Hex(), BgColor: b.Hex(),
+		Ratio: contrastRatio(f, b), Min: c.opts.min(),
+	}
+	out := fg
+	if !chk.OK() && c.opts.AutoAdjust {
+		adj := adjustContrast(f, b, chk.Min)
+		chk.Adjusted, chk.NewRatio = adj.Hex(), contrastRatio(adj, b)
+		out = withHexForm(fg, adj)
+	}
+	c.checks = append(c.checks, chk)
+	return out
+}
+
+// report adds adjustments as actions and remaining failures as warnings.
+func (c *contrastChecker) report(rep *Report) {
+	for _, chk := range c.checks {
+		switch {
+		case chk.Adjusted != "":
+			rep.did("contrast %s", chk)
+			if chk.NewRatio < chk.Min {
+				rep.warn("contrast %s: %.1f:1 is as far as it goes", chk.Target+" "+chk.Fg, chk.NewRatio)
+			}
+		case !chk.OK():
+			rep.warn("contrast %s", chk)
+		}
+	}
+}
+
+func parseHexColor(s string) (theme.RGB, bool) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 8 {
+		s = s[:6] // rrggbbaa
+	}
+	if len(s) != 6 {
+		return theme.RGB{}, false
+	}
+	return theme.ParseKittyColor("#" + s)
+}
+
+// withHexForm writes c the way orig was written: with or without '#',
+// keeping any alpha suffix and the case of the digits.
+func withHexForm(orig string, c theme.RGB) string {
+	hex := c.Hex()[1:]
+	body := strings.TrimPrefix(orig, "#")
+	if strings.ToUpper(body) == body && strings.ToLower(body) != body {
+		hex = strings.ToUpper(hex)
+	}
+	if len(body) == 8 {
+		hex += body[6:]
+	}
+	if strings.HasPrefix(orig, "#") {
+		hex = "#" + hex
+	}
+	return hex
+}
+
+// contrastRatio is WCAG 2's (L1 + 0.05) / (L2 + 0.05).
+func contrastRatio(a, b theme.RGB) float64 {
+	la, lb := luminance(a), luminance(b)
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+func luminance(c theme.RGB) float64 {
+	ch := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.04045 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*ch(c.R) + 0.7152*ch(c.G) + 0.0722*ch(c.B)
+}
+
+// adjustContrast moves fg's HSL lightness away from bg, keeping hue and
+// saturation, until the ratio reaches min. If neither direction gets
+// there it returns the best it found.
+func adjustContrast(fg, bg theme.RGB, min float64) theme.RGB {
+	h, s, l := toHSL(fg)
+	best, bestRatio := fg, contrastRatio(fg, bg)
+	// Try the direction with more room first.
+	dirs := []float64{1, -1}
+	if luminance(bg) > 0.18 {
+		dirs = []float64{-1, 1}
+	}
+	for _, dir := range dirs {
+		for step := 1; step <= 100; step++ {
+			nl := l + dir*float64(step)/100
+			if nl < 0 || nl > 1 {
+				break
+			}
+			c := fromHSL(h, s, nl)
+			r := contrastRatio(c, bg)
+			if r > bestRatio {
+				best, bestRatio = c, r
+			}
+			if r >= min {
+				return c
+			}
+		}
+	}
+	return best
+}
+
+func toHSL(c theme.RGB) (h, s, l float64) {
+	r, g, b := float64(c.R)/255, float64(c.G)/255, float64(c.B)/255
+	max, min := math.Max(r, math.Max(g, b)), math.Min(r, math.Min(g, b))
+	l = (max + min) / 2
+	if max == min {
+		return 0, 0, l
+	}
+	d := max - min
+	if l > 0.5 {
+		s = d / (2 - max - min)
+	} else {
+		s = d / (max + min)
+	}
+	switch max {
+	case r:
+		h = (g - b) / d
+		if g < b {
+			h += 6
+		}
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return h / 6, s, l
+}
+
+func fromHSL(h, s, l float64) theme.RGB {
+	if s == 0 {
+		v := uint8(math.Round(l * 255))
+		return theme.RGB{R: v, G: v, B: v}
+	}
+	q := l * (1 + s)
+	if l >= 0.5 {
+		q = l + s - l*s
+	}
+	p := 2*l - q
+	hue := func(t float64) uint8 {
+		if t < 0 {
+			t++
+		}
+		if t > 1 {
+			t--
+		}
+		var v float64
+		switch {
+		case t < 1.0/6:
+			v = p + (q-p)*6*t
+		case t < 0.5:
+			v = q
+		case t < 2.0/3:
+			v = p + (q-p)*(2.0/3-t)*6
+		default:
+			v = p
+		}
+		return uint8(math.Round(v * 255))
+	}
+	return theme.RGB{R: hue(h + 1.0/3), G: hue(h), B: hue(h - 1.0/3)}
+}
+
+// KittyContrast runs the checks an apply would for a kitty theme, without
+// writing anything, for the TUI's Kitty pane.
+func KittyContrast(themeName string, opts Options) ([]ContrastCheck, error) {
+	colors, err := kittyPalette(themeName)
+	if err != nil {
+		return nil, err
+	}
+	fz := newContrastChecker("fuzzel", opts.Contrast)
+	fuzzelColors(colors, opts.Fuzzel, fz)
+	dec := newContrastChecker("decorations", opts.Contrast)
+	decorationColors(colors, dec)
+	return append(fz.checks, dec.checks...), nil
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// flatpakFilesystems are the read-only mounts that let sandboxed apps see
+// user themes and GTK config.
+var flatpakFilesystems = []string{
+	"~/.local/share/themes:ro",
+	"~/.local/share/icons:ro",
+	"xdg-config/gtk-3.0:ro",
+	"xdg-config/gtk-4.0:ro",
+}
+
+// flatpakStep keeps the global Flatpak overrides in step with the GTK and
+// icon themes when Options.FlatpakOverrides is on. It only adds its own
+// filesystems entries and sets GTK_THEME/ICON_THEME; every other line of
+// the file is kept. Once the option is off, the entries it added and the
+// variables it set (both recorded in theme.FlatpakEnvPath) are removed
+// again.
+func flatpakStep(sel Selections, opts Options, rep *Report) error {
+	set, err := loadStateMap(theme.FlatpakEnvPath(), "flatpak env")
+	if err != nil {
+		return err
+	}
+	if !opts.FlatpakOverrides && len(set) == 0 {
+		return nil
+	}
+	if opts.FlatpakOverrides && sel.GtkTheme == "" && sel.IconTheme == "" {
+		return nil
+	}
+	saved := maps.Clone(set)
+	path := theme.FlatpakOverridesPath()
+	old, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read flatpak overrides: %w", err)
+	}
+	ini := splitIni(string(old))
+
+	if opts.FlatpakOverrides {
+		flatpakGrant(ini, set, rep)
+		env := []struct{ key, value string }{{"GTK_THEME", sel.GtkTheme}, {"ICON_THEME", sel.IconTheme}}
+		for _, e := range env {
+			if e.value == "" {
+				continue
+			}
+			if ini.get("Environment", e.key) != e.value {
+				ini.set("Environment", e.key, e.value)
+				rep.did("flatpak overrides: %s=%s", e.key, e.value)
+				set[e.key] = e.value
+			}
+		}
+	} else {
+		for key, value := range set {
+			if key == flatpakGrantsKey {
+				flatpakRevoke(ini, strings.Split(value, ";"), rep)
+				delete(set, key)
+				continue
+			}
+			// Changed by hand since; that's the user's now.
+			if ini.get("Environment", key) == value {
+				ini.del("Environment", key)
+				rep.did("flatpak overrides: unset %s", key)
+			}
+			delete(set, key)
+		}
+	}
+
+	if opts.DryRun {
+		return nil
+	}
+	if out := ini.String(); out != string(old) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return fmt.Errorf("write flatpak overrides: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(out), 0o644); err != nil {
+			return fmt.Errorf("write flatpak overrides: %w", err)
+		}
+	}
+	if maps.Equal(set, saved) {
+		return nil
+	}
+	return saveStateMap(theme.FlatpakEnvPath(), "flatpak env", set)
+}
+
+// flatpakGrantsKey is where the state map lists the filesystems entries
+// flatpakGrant added, next to the variables set.
+const flatpakGrantsKey = "filesystems"
+
+// flatpakGrant adds the flatpakFilesystems entries the file has no say on
+// and records them in set.
+func flatpakGrant(ini *keyFile, set map[string]string, rep *Report) {
+	fs := strings.Split(ini.get("Context", "filesystems"), ";")
+	var added []string
+	for _, want := range flatpakFilesystems {
+		if !flatpakHasFilesystem(fs, want) {
+			added = append(added, want)
+		}
+	}
+	if len(added) == 0 {
+		return
+	}
+	list := strings.TrimSuffix(ini.get("Context", "filesystems"), ";")
+	if list != "" {
+		list += ";"
+	}
+	ini.set("Context", "filesystems", list+strings.Join(added, ";")+";")
+	rep.did("flatpak overrides: filesystems %s", strings.Join(added, " "))
+	if prev := set[flatpakGrantsKey]; prev != "" {
+		added = append(strings.Split(prev, ";"), added...)
+	}
+	set[flatpakGrantsKey] = strings.Join(added, ";")
+}
+
+// flatpakRevoke removes the given entries from filesystems, as long as
+// they're still there as flatpakGrant wrote them.
+func flatpakRevoke(ini *keyFile, granted []string, rep *Report) {
+	var keep, removed []string
+	for _, f := range strings.Split(ini.get("Context", "filesystems"), ";") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		if slices.Contains(granted, f) && !slices.Contains(removed, f) {
+			removed = append(removed, f)
+			continue
+		}
+		keep = append(keep, f)
+	}
+	if len(removed) == 0 {
+		return
+	}
+	if len(keep) == 0 {
+		ini.del("Context", "filesystems")
+	} else {
+		ini.set("Context", "filesystems", strings.Join(keep, ";")+";")
+	}
+	rep.did("flatpak overrides: remove filesystems %s", strings.Join(removed, " "))
+}
+
+// flatpakHasFilesystem treats "x", "x:ro" and "x:rw" as the same mount,
+// and a denied "!x" as present: the user decided against it.
+func flatpakHasFilesystem(list []string, want string) bool {
+	base, _, _ := strings.Cut(want, ":")
+	for _, f := range list {
+		b, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(f), "!"), ":")
+		if b == base {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// gtk4Entries are what libadwaita loads from ~/.config/gtk-4.0.
+var gtk4Entries = []string{"gtk.css", "gtk-dark.css", "assets"}
+
+const gtk4BackupSuffix = ".labwcchanger-backup"
+
+// gtk4LinkStep points ~/.config/gtk-4.0/{gtk.css,gtk-dark.css,assets} at
+// the selected theme's gtk-4.0 files when Options.LinkGtk4 is on. Files of
+// the user's own are renamed to a backup first; our links are removed,
+// and the backup put back, once the option is off or the theme has no
+// gtk-4.0 support. Links count as ours only while they still point where
+// theme.Gtk4LinksPath says we pointed them.
+func gtk4LinkStep(sel Selections, opts Options, rep *Report) error {
+	links, err := loadStateMap(theme.Gtk4LinksPath(), "gtk-4.0 links")
+	if err != nil {
+		return err
+	}
+	if !opts.LinkGtk4 && len(links) == 0 {
+		// Never turned on, or nothing of ours is left.
+		return nil
+	}
+	if opts.LinkGtk4 && sel.GtkTheme == "" {
+		return nil
+	}
+	saved := maps.Clone(links)
+	src := ""
+	if opts.LinkGtk4 {
+		if src = theme.Gtk4Dir(sel.GtkTheme); src == "" {
+			rep.warn("GTK theme %s has no gtk-4.0 support, libadwaita left unthemed", sel.GtkTheme)
+		}
+	}
+	var linkErr error
+	for _, entry := range gtk4Entries {
+		target := ""
+		if src != "" {
+			if _, err := os.Stat(filepath.Join(src, entry)); err == nil {
+				target = filepath.Join(src, entry)
+			}
+		}
+		if linkErr = linkGtk4Entry(filepath.Join(theme.Gtk4ConfigDir(), entry), target, links, opts, rep); linkErr != nil {
+			// Still record the links made so far, or they'd never count as ours.
+			break
+		}
+	}
+	if opts.DryRun || maps.Equal(links, saved) {
+		return linkErr
+	}
+	return errors.Join(linkErr, saveStateMap(theme.Gtk4LinksPath(), "gtk-4.0 links", links))
+}
+
+// linkGtk4Entry makes dst a link to target, or removes our link when
+// target is "", keeping links up to date.
+func linkGtk4Entry(dst, target string, links map[string]string, opts Options, rep *Report) error {
+	cur, err := os.Readlink(dst)
+	isLink := err == nil
+	ours := isLink && links[dst] == cur
+	if !ours {
+		// Replaced or removed by the user since; not ours to touch.
+		delete(links, dst)
+	}
+	_, statErr := os.Lstat(dst)
+	present := statErr == nil
+	backup := dst + gtk4BackupSuffix
+	_, err = os.Lstat(backup)
+	backedUp := err == nil
+
+	if target == "" {
+		if !ours {
+			return nil
+		}
+		rep.did("unlink %s", dst)
+		if backedUp {
+			rep.did("restore %s", dst)
+		}
+		if opts.DryRun {
+			return nil
+		}
+		if err := os.Remove(dst); err != nil {
+			return fmt.Errorf("unlink gtk-4.0: %w", err)
+		}
+		delete(links, dst)
+		if backedUp {
+			if err := os.Rename(backup, dst); err != nil {
+				return fmt.Errorf("restore gtk-4.0: %w", err)
+			}
+		}
+		return nil
+	}
+
+	if ours && cur == target {
+		return nil
+	}
+	if present && !ours {
+		if backedUp {
+			rep.warn("%s and %s both exist, not linked", dst, filepath.Base(backup))
+			return nil
+		}
+		rep.did("back up %s to %s", dst, filepath.Base(backup))
+	}
+	rep.did("link %s -> %s", dst, target)
+	if opts.DryRun {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return fmt.Errorf("link gtk-4.0: %w", err)
+	}
+	switch {
+	case present && !ours:
+		if err := os.Rename(dst, backup); err != nil {
+			return fmt.Errorf("back up gtk-4.0: %w", err)
+		}
+	case ours:
+		if err := os.Remove(dst); err != nil {
+			return fmt.Errorf("link gtk-4.0: %w", err)
+		}
+		delete(links, dst)
+	}
+	if err := os.Symlink(target, dst); err != nil {
+		return fmt.Errorf("link gtk-4.0: %w", err)
+	}
+	links[dst] = target
+	return nil
+}
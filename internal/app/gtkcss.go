@@ -0,0 +1,63 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// gtkCssStep writes libadwaita @define-color overrides derived from the
+// kitty palette to gtk-4.0/gtk.css and gtk-3.0/gtk.css when
+// Options.GtkCssOverride is on, and takes them out again when it's off.
+func gtkCssStep(sel Selections, opts Options, rep *Report) error {
+	block := ""
+	if opts.GtkCssOverride {
+		if sel.KittyTheme == "" {
+			return nil
+		}
+		colors, err := kittyPalette(sel.KittyTheme)
+		if err != nil {
+			return err
+		}
+		chk := newContrastChecker("gtk.css", opts.Contrast)
+		block = gtkColorBlock(sel.KittyTheme, decorationColors(colors, chk))
+		chk.report(rep)
+	}
+	for _, path := range []string{theme.Gtk4CssPath(), theme.Gtk3CssPath()} {
+		if err := spliceColorBlock(path, block, false, opts, rep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gtkColorBlock maps the palette onto libadwaita's named colors; GTK3
+// themes built on Adwaita use the same names.
+func gtkColorBlock(kittyTheme string, d decoration) string {
+	card := "mix(" + d.bg + ", " + d.fg + ", 0.05)"
+	lines := []string{
+		colorBlockBegin,
+		"/* from the kitty theme " + kittyTheme + " */",
+		"@define-color accent_color " + d.accent + ";",
+		"@define-color accent_bg_color " + d.accent + ";",
+		"@define-color accent_fg_color " + d.onAccent + ";",
+		"@define-color window_bg_color " + d.bg + ";",
+		"@define-color window_fg_color " + d.fg + ";",
+		"@define-color view_bg_color " + d.bg + ";",
+		"@define-color view_fg_color " + d.fg + ";",
+		"@define-color headerbar_bg_color " + d.inactiveBg + ";",
+		"@define-color headerbar_fg_color " + d.fg + ";",
+		"@define-color headerbar_backdrop_color " + d.bg + ";",
+		"@define-color card_bg_color " + card + ";",
+		"@define-color card_fg_color " + d.fg + ";",
+		"@define-color popover_bg_color " + d.bg + ";",
+		"@define-color popover_fg_color " + d.fg + ";",
+		"@define-color dialog_bg_color " + d.bg + ";",
+		"@define-color dialog_fg_color " + d.fg + ";",
+		"@define-color sidebar_bg_color " + d.inactiveBg + ";",
+		"@define-color sidebar_fg_color " + d.fg + ";",
+		colorBlockEnd,
+		"",
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,89 @@
+package app
+
+import "strings"
+
+// keyFile is an ini file (GKeyFile, fuzzel.ini, tofi's config) kept as lines so
+// comments, ordering and unknown keys survive edits.
+type keyFile struct {
+	lines []string
+}
+
+func splitIni(s string) *keyFile {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return &keyFile{}
+	}
+	return &keyFile{lines: strings.Split(s, "\n")}
+}
+
+// find returns the line index of key in section (-1 if absent) and the
+// index just past the section's last non-blank line (-1 if no section).
+func (k *keyFile) find(section, key string) (at, end int) {
+	at, end = -1, -1
+	// Keys before the first header, as in tofi's config, are section "".
+	in := section == ""
+	if in {
+		end = 0
+	}
+	for i, l := range k.lines {
+		t := strings.TrimSpace(l)
+		if strings.HasPrefix(t, "[") && strings.HasSuffix(t, "]") {
+			in = t == "["+section+"]"
+			if in {
+				end = i + 1
+			}
+			continue
+		}
+		if !in {
+			continue
+		}
+		if t != "" {
+			end = i + 1
+		}
+		if name, _, ok := strings.Cut(t, "="); ok && strings.TrimSpace(name) == key && at < 0 {
+			at = i
+		}
+	}
+	return at, end
+}
+
+func (k *keyFile) get(section, key string) string {
+	at, _ := k.find(section, key)
+	if at < 0 {
+		return ""
+	}
+	_, v, _ := strings.Cut(k.lines[at], "=")
+	return strings.TrimSpace(v)
+}
+
+func (k *keyFile) set(section, key, value string) {
+	line := key + "=" + value
+	at, end := k.find(section, key)
+	switch {
+	case at >= 0:
+		// Keep the key's own spelling and spacing ("key = value").
+		name, v, _ := strings.Cut(k.lines[at], "=")
+		k.lines[at] = name + "=" + v[:len(v)-len(strings.TrimLeft(v, " \t"))] + value
+	case end >= 0:
+		k.lines = append(k.lines[:end], append([]string{line}, k.lines[end:]...)...)
+	default:
+		if len(k.lines) > 0 && strings.TrimSpace(k.lines[len(k.lines)-1]) != "" {
+			k.lines = append(k.lines, "")
+		}
+		k.lines = append(k.lines, "["+section+"]", line)
+	}
+}
+
+// del removes key from section, leaving an empty section in place.
+func (k *keyFile) del(section, key string) {
+	if at, _ := k.find(section, key); at >= 0 {
+		k.lines = append(k.lines[:at], k.lines[at+1:]...)
+	}
+}
+
+func (k *keyFile) String() string {
+	if len(k.lines) == 0 {
+		return ""
+	}
+	return strings.Join(k.lines, "\n") + "\n"
+}
@@ -0,0 +1,169 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// kittyThemeInclude is the block `kitten themes` adds to kitty.conf; we
+// add the same one so either mode can take over from the other.
+const kittyThemeInclude = "# BEGIN_KITTY_THEME\ninclude current-theme.conf\n# END_KITTY_THEME\n"
+
+// applyKittyDirect does what `kitten themes` does without its registry:
+// the resolved theme file becomes current-theme.conf, kitty.conf includes
+// it, and running kittys get the new colors over remote control.
+func applyKittyDirect(themeName string, rep *Report) error {
+	p, err := resolveKittyThemeFile(themeName)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return fmt.Errorf("read kitty theme: %w", err)
+	}
+	current := theme.KittyCurrentThemePath()
+	if err := os.MkdirAll(filepath.Dir(current), 0o755); err != nil {
+		return fmt.Errorf("write current-theme.conf: %w", err)
+	}
+	if err := os.WriteFile(current, b, 0o644); err != nil {
+		return fmt.Errorf("write current-theme.conf: %w", err)
+	}
+	if err := ensureKittyInclude(rep); err != nil {
+		return err
+	}
+	pushKittyColors(current, rep)
+	return nil
+}
+
+// ensureKittyInclude appends the include to kitty.conf, creating it if
+// needed, unless kitty.conf already includes current-theme.conf.
+func ensureKittyInclude(rep *Report) error {
+	conf := theme.KittyConfPath()
+	b, err := os.ReadFile(conf)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read kitty.conf: %w", err)
+	}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) == 2 && f[0] == "include" && filepath.Base(f[1]) == "current-theme.conf" {
+			return nil
+		}
+	}
+	if len(b) > 0 && !bytes.HasSuffix(b, []byte("\n")) {
+		b = append(b, '\n')
+	}
+	if len(b) > 0 {
+		b = append(b, '\n')
+	}
+	b = append(b, kittyThemeInclude...)
+	if err := os.WriteFile(conf, b, 0o644); err != nil {
+		return fmt.Errorf("write kitty.conf: %w", err)
+	}
+	rep.did("kitty.conf: include current-theme.conf")
+	return nil
+}
+
+// pushKittyColors sends the colors to every running kitty. Instances
+// listening on a remote-control socket get `kitten @ set-colors`; the rest
+// are told to reload their config, which has the same effect.
+func pushKittyColors(current string, rep *Report) {
+	listen := kittyListenOn()
+	for _, pid := range pidsOf("kitty") {
+		if sock := kittySocketOf(pid, listen); sock != "" {
+			err := run("kitten", "@", "--to", sock, "set-colors", "--all", "--configured", current)
+			if err == nil {
+				continue
+			}
+			rep.warn("kitty %d: set-colors over %s failed, reloading config instead", pid, sock)
+		}
+		_ = syscall.Kill(pid, syscall.SIGUSR1)
+	}
+}
+
+// kittyListenOn reads listen_on from kitty.conf, "" when remote control
+// isn't listening anywhere.
+func kittyListenOn() string {
+	b, err := os.ReadFile(theme.KittyConfPath())
+	if err != nil {
+		return ""
+	}
+	listen := ""
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) >= 2 && f[0] == "listen_on" {
+			listen = f[1] // the last one wins, as in kitty
+		}
+	}
+	return listen
+}
+
+// kittySocketOf finds the socket of the kitty with this pid, most
+// specific source first: KITTY_LISTEN_ON when we run inside that kitty,
+// then --listen-on on its command line, then listen_on from kitty.conf.
+func kittySocketOf(pid int, confListen string) string {
+	p := strconv.Itoa(pid)
+	if env := os.Getenv("KITTY_LISTEN_ON"); env != "" && os.Getenv("KITTY_PID") == p {
+		if sock := kittySocket(env, pid, false); sock != "" {
+			return sock
+		}
+	}
+	if listen := kittyCmdlineListenOn(pid); listen != "" {
+		return kittySocket(listen, pid, false)
+	}
+	return kittySocket(confListen, pid, true)
+}
+
+// kittyCmdlineListenOn returns the --listen-on given to the kitty with
+// this pid, the last one if there are several.
+func kittyCmdlineListenOn(pid int) string {
+	b, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return ""
+	}
+	args := strings.Split(strings.TrimSuffix(string(b), "\x00"), "\x00")
+	listen := ""
+	for i, a := range args {
+		if v, ok := strings.CutPrefix(a, "--listen-on="); ok {
+			listen = v
+		} else if a == "--listen-on" && i+1 < len(args) {
+			listen = args[i+1]
+		}
+	}
+	return listen
+}
+
+// kittySocket is the socket address of the kitty with this pid. kitty
+// appends "-<pid>" to a listen_on from kitty.conf (confSuffix) unless it
+// says where the pid goes with {kitty_pid}; --listen-on is used as given.
+func kittySocket(listen string, pid int, confSuffix bool) string {
+	path, ok := strings.CutPrefix(listen, "unix:")
+	if !ok || path == "" {
+		return ""
+	}
+	p := strconv.Itoa(pid)
+	if strings.Contains(path, "{kitty_pid}") {
+		path = strings.ReplaceAll(path, "{kitty_pid}", p)
+	} else if confSuffix {
+		path += "-" + p
+	}
+	if strings.HasPrefix(path, "@") {
+		return "unix:" + path // abstract socket, nothing to stat
+	}
+	if strings.HasPrefix(path, "~/") {
+		path = filepath.Join(theme.HomeDir(), path[2:])
+	}
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return "unix:" + path
+}
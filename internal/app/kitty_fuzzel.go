@@ -1,7 +1,6 @@
 package app
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -12,29 +11,28 @@ import (
 )
 
 func resolveKittyThemeFile(themeName string) (string, error) {
-	dir := theme.KittyThemesDir()
-	entries, err := os.ReadDir(dir)
-	if err == nil {
-		for _, e := range entries {
-			if e.IsDir() {
-				continue
-			}
-			name := e.Name()
-			if strings.ToLower(filepath.Ext(name)) != ".conf" {
-				continue
-			}
-			if strings.TrimSuffix(name, filepath.Ext(name)) == themeName {
-				return filepath.Join(dir, name), nil
-			}
-		}
-	}
-	fallback := filepath.Join(dir, themeName+".conf")
-	if _, err := os.Stat(fallback); err == nil {
-		return fallback, nil
+	if p := theme.KittyThemeFile(themeName); p != "" {
+		return p, nil
 	}
 	return "", ErrKittyThemeNotFound
 }
 
+// kittyPalette reads a theme, includes and all, into option name →
+// RRGGBB. A partly broken theme still gives the colors that parsed;
+// LoadKittyColors reports the rest.
+func kittyPalette(themeName string) (map[string]string, error) {
+	p, err := resolveKittyThemeFile(themeName)
+	if err != nil {
+		return nil, err
+	}
+	kc := theme.LoadKittyColors(p)
+	out := make(map[string]string, len(kc.Colors))
+	for k, c := range kc.Colors {
+		out[k] = strings.ToUpper(c.Hex()[1:])
+	}
+	return out, nil
+}
+
 func applyKittyTheme(themeName string) error {
 	// `kitten themes` expects the theme NAME from kitty's registry,
 	// not the filename. Theme files may use underscores in filename
@@ -84,25 +82,6 @@ func getKittyThemeName(themeName string) string {
 	return ""
 }
 
-func parseKittyTheme(content string) map[string]string {
-	out := map[string]string{}
-	s := bufio.NewScanner(strings.NewReader(content))
-	// Matches: key  #RRGGBB
-	re := regexp.MustCompile(`^([A-Za-z0-9_-]+)\s+#([0-9A-Fa-f]{6})`)
-	for s.Scan() {
-		line := strings.TrimRight(s.Text(), "\r\n")
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
-			continue
-		}
-		m := re.FindStringSubmatch(trimmed)
-		if len(m) == 3 {
-			out[m[1]] = strings.ToUpper(m[2])
-		}
-	}
-	return out
-}
-
 func parseKittyMeta(content, key string) string {
 	// Matches: ## name: Something
 	re := regexp.MustCompile(`(?m)^##\s*` + regexp.QuoteMeta(key) + `\s*:\s*(.+)$`)
@@ -113,62 +92,189 @@ func parseKittyMeta(content, key string) string {
 	return ""
 }
 
-func updateFuzzelColors(selectedKittyTheme string) error {
-	p, err := resolveKittyThemeFile(selectedKittyTheme)
-	if err != nil {
-		return err
+// FuzzelOptions configure how a kitty theme becomes fuzzel colors.
+type FuzzelOptions struct {
+	// Colors overrides the default mapping per fuzzel [colors] key.
+	Colors map[string]FuzzelColor `json:"colors,omitempty"`
+}
+
+// FuzzelColor says where a fuzzel color comes from: the first kitty
+// option in From the theme sets (a "#rrggbb" entry is used as is), at
+// Alpha opacity (0-1, default opaque).
+type FuzzelColor struct {
+	From  []string `json:"from,omitempty"`
+	Alpha *float64 `json:"alpha,omitempty"`
+}
+
+// fuzzelColorKeys are fuzzel's [colors] options.
+var fuzzelColorKeys = []string{
+	"background", "text", "prompt", "placeholder", "input", "match",
+	"selection", "selection-text", "selection-match", "counter", "border",
+}
+
+func alpha(a float64) *float64 { return &a }
+
+// defaultFuzzelColors is the Flutter app's heuristic mapping.
+var defaultFuzzelColors = map[string]FuzzelColor{
+	"background":      {From: []string{"inactive_tab_background", "selection_background", "background", "#000000"}, Alpha: alpha(0xf2 / 255.0)},
+	"text":            {From: []string{"foreground", "cursor", "#ffffff"}},
+	"match":           {From: []string{"color4", "active_border_color", "color12", "foreground", "cursor", "#ffffff"}},
+	"selection":       {From: []string{"inactive_tab_foreground", "color8", "foreground", "cursor", "#ffffff"}},
+	"selection-text":  {From: []string{"selection_foreground", "foreground", "cursor", "#ffffff"}},
+	"selection-match": {From: []string{"color4", "active_border_color", "color12", "foreground", "cursor", "#ffffff"}},
+	"border":          {From: []string{"color4", "active_border_color", "color12", "foreground", "cursor", "#ffffff"}},
+}
+
+// Mapping is the default mapping with the configured keys replaced.
+func (o FuzzelOptions) Mapping() map[string]FuzzelColor {
+	m := make(map[string]FuzzelColor, len(defaultFuzzelColors)+len(o.Colors))
+	for k, c := range defaultFuzzelColors {
+		m[k] = c
 	}
-	b, err := os.ReadFile(p)
-	if err != nil {
-		return fmt.Errorf("read kitty theme: %w", err)
+	for k, c := range o.Colors {
+		if len(c.From) == 0 {
+			c.From = m[k].From
+		}
+		if c.Alpha == nil {
+			c.Alpha = m[k].Alpha
+		}
+		m[k] = c
 	}
-	content := string(b)
-	colors := parseKittyTheme(content)
+	return m
+}
 
-	schemeName := parseKittyMeta(content, "name")
-	if schemeName == "" {
-		schemeName = selectedKittyTheme
+func (o FuzzelOptions) Validate() error {
+	for k, c := range o.Colors {
+		if !oneOf(k, fuzzelColorKeys) {
+			return fmt.Errorf("fuzzel color %q: must be one of %s", k, strings.Join(fuzzelColorKeys, ", "))
+		}
+		for _, f := range c.From {
+			if strings.HasPrefix(f, "#") {
+				if _, ok := theme.ParseKittyColor(f); !ok {
+					return fmt.Errorf("fuzzel color %s: %q is not a color", k, f)
+				}
+			} else if !theme.IsKittyColorKey(f) {
+				return fmt.Errorf("fuzzel color %s: %q is not a kitty color option", k, f)
+			}
+		}
+		if c.Alpha != nil && (*c.Alpha < 0 || *c.Alpha > 1) {
+			return fmt.Errorf("fuzzel color %s: alpha %v: must be 0-1", k, *c.Alpha)
+		}
 	}
-	schemeAuthor := parseKittyMeta(content, "author")
-	if schemeAuthor == "" {
-		schemeAuthor = "unknown"
+	return nil
+}
+
+// resolve picks the color for c from a kittyPalette map as rrggbbaa.
+func (c FuzzelColor) resolve(colors map[string]string) (string, bool) {
+	for _, f := range c.From {
+		hex := colors[f]
+		if strings.HasPrefix(f, "#") {
+			if rgb, ok := theme.ParseKittyColor(f); ok {
+				hex = rgb.Hex()[1:]
+			}
+		}
+		if hex == "" {
+			continue
+		}
+		a := 1.0
+		if c.Alpha != nil {
+			a = *c.Alpha
+		}
+		return strings.ToLower(hex) + fmt.Sprintf("%02x", int(a*255+0.5)), true
 	}
+	return "", false
+}
 
-	// Same heuristic mapping as Flutter.
-	base05 := strings.ToLower(firstNonEmpty(colors["foreground"], colors["cursor"], "FFFFFF"))
-	base00 := strings.ToLower(firstNonEmpty(colors["background"], "000000"))
-	base01 := strings.ToLower(firstNonEmpty(colors["inactive_tab_background"], colors["selection_background"], strings.ToUpper(base00)))
-	base03 := strings.ToLower(firstNonEmpty(colors["inactive_tab_foreground"], colors["color8"], strings.ToUpper(base05)))
-	base06 := strings.ToLower(firstNonEmpty(colors["selection_foreground"], colors["foreground"], strings.ToUpper(base05)))
-	base0D := strings.ToLower(firstNonEmpty(colors["color4"], colors["active_border_color"], colors["color12"], strings.ToUpper(base05)))
+// fuzzelContrastPairs are the fuzzel text colors and what they're drawn on.
+var fuzzelContrastPairs = [][2]string{
+	{"text", "background"}, {"prompt", "background"}, {"input", "background"},
+	{"placeholder", "background"}, {"counter", "background"}, {"match", "background"},
+	{"selection-text", "selection"}, {"selection-match", "selection"},
+}
 
-	fuzzelPath := theme.FuzzelIniPath()
+// fuzzelColors maps the palette onto fuzzel's [colors] keys, as rrggbbaa.
+func fuzzelColors(colors map[string]string, o FuzzelOptions, chk *contrastChecker) map[string]string {
+	vals := map[string]string{}
+	mapping := o.Mapping()
+	for _, k := range fuzzelColorKeys {
+		if c, ok := mapping[k]; ok {
+			if v, ok := c.resolve(colors); ok {
+				vals[k] = v
+			}
+		}
+	}
+	for _, p := range fuzzelContrastPairs {
+		fg, ok1 := vals[p[0]]
+		bg, ok2 := vals[p[1]]
+		if ok1 && ok2 {
+			vals[p[0]] = chk.fix(p[0], fg, p[1], bg)
+		}
+	}
+	return vals
+}
 
+// updateFuzzelColors sets the [colors] keys of fuzzel.ini from the kitty
+// theme and leaves every other line, fonts and keybindings included, as
+// it was. A missing fuzzel.ini is created with just the colors.
+func updateFuzzelColors(selectedKittyTheme string, opts Options, rep *Report) error {
+	colors, err := kittyPalette(selectedKittyTheme)
+	if err != nil {
+		return err
+	}
+	fuzzelPath := theme.FuzzelIniPath()
+	old, err := os.ReadFile(fuzzelPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read fuzzel.ini: %w", err)
+	}
+	ini := splitIni(string(old))
+	header := splitIni(fuzzelHeader(selectedKittyTheme)).lines
+	switch {
+	case len(old) == 0:
+		ini.lines = header
+	case isFuzzelHeader(ini.lines):
+		// Ours; keep it naming the theme the colors come from.
+		copy(ini.lines, header)
+	}
+	chk := newContrastChecker("fuzzel", opts.Contrast)
+	vals := fuzzelColors(colors, opts.Fuzzel, chk)
+	chk.report(rep)
+	for _, k := range fuzzelColorKeys {
+		if v, ok := vals[k]; ok {
+			ini.set("colors", k, v)
+		}
+	}
+	out := ini.String()
+	if out == string(old) {
+		return nil
+	}
 	if err := os.MkdirAll(filepath.Dir(fuzzelPath), 0o755); err != nil {
 		return fmt.Errorf("mkdir fuzzel dir: %w", err)
 	}
-
-	out := strings.Join([]string{
-		"## " + schemeName + " theme",
-		"## by " + schemeAuthor,
-		"",
-		"[colors]",
-		"background=" + base01 + "f2",
-		"text=" + base05 + "ff",
-		"match=" + base0D + "ff",
-		"selection=" + base03 + "ff",
-		"selection-text=" + base06 + "ff",
-		"selection-match=" + base0D + "ff",
-		"border=" + base0D + "ff",
-		"",
-	}, "\n")
-
 	if err := os.WriteFile(fuzzelPath, []byte(out), 0o644); err != nil {
 		return fmt.Errorf("write fuzzel.ini: %w", err)
 	}
 	return nil
 }
 
+func fuzzelHeader(kittyTheme string) string {
+	name, author := kittyTheme, "unknown"
+	if p, err := resolveKittyThemeFile(kittyTheme); err == nil {
+		if b, err := os.ReadFile(p); err == nil {
+			name = firstNonEmpty(parseKittyMeta(string(b), "name"), name)
+			author = firstNonEmpty(parseKittyMeta(string(b), "author"), author)
+		}
+	}
+	return themercMarker + "\n## " + name + " theme\n## by " + author + "\n"
+}
+
+// isFuzzelHeader reports whether lines start with what fuzzelHeader
+// writes. Only a header under our marker counts; a comment of the user's
+// that merely looks like one is left alone.
+func isFuzzelHeader(lines []string) bool {
+	return len(lines) >= 3 && lines[0] == themercMarker &&
+		strings.HasPrefix(lines[1], "## ") && strings.HasPrefix(lines[2], "## by ")
+}
+
 func firstNonEmpty(values ...string) string {
 	for _, v := range values {
 		if strings.TrimSpace(v) != "" {
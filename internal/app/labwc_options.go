@@ -0,0 +1,284 @@
+package app
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LabwcOptions are the <theme> settings in rc.xml besides name and icon.
+// Unset fields leave rc.xml as it is.
+type LabwcOptions struct {
+	CornerRadius   *int                 `json:"corner_radius,omitempty"`
+	DropShadows    *bool                `json:"drop_shadows,omitempty"`
+	KeepBorder     *bool                `json:"keep_border,omitempty"`
+	TitlebarLayout string               `json:"titlebar_layout,omitempty"`
+	Fonts          map[string]LabwcFont `json:"fonts,omitempty"` // by place
+}
+
+// LabwcFont is a <font place="..."> element. Zero fields are left alone.
+type LabwcFont struct {
+	Name   string `json:"name,omitempty"`
+	Size   int    `json:"size,omitempty"`
+	Slant  string `json:"slant,omitempty"`
+	Weight string `json:"weight,omitempty"`
+}
+
+// Allowed values, from labwc's rc.xml documentation.
+var (
+	LabwcFontPlaces  = []string{"ActiveWindow", "InactiveWindow", "MenuHeader", "MenuItem", "OnScreenDisplay"}
+	labwcButtons     = []string{"icon", "menu", "iconify", "max", "close", "shade", "desk"}
+	labwcFontSlants  = []string{"normal", "italic", "oblique"}
+	labwcFontWeights = []string{"normal", "bold"}
+)
+
+const maxCornerRadius = 64
+
+func (o LabwcOptions) IsZero() bool {
+	return o.CornerRadius == nil && o.DropShadows == nil && o.KeepBorder == nil &&
+		o.TitlebarLayout == "" && len(o.Fonts) == 0
+}
+
+// Overlay returns o with every set field of p copied over it. Fonts merge
+// per place and per field.
+func (o LabwcOptions) Overlay(p LabwcOptions) LabwcOptions {
+	if p.CornerRadius != nil {
+		o.CornerRadius = p.CornerRadius
+	}
+	if p.DropShadows != nil {
+		o.DropShadows = p.DropShadows
+	}
+	if p.KeepBorder != nil {
+		o.KeepBorder = p.KeepBorder
+	}
+	if p.TitlebarLayout != "" {
+		o.TitlebarLayout = p.TitlebarLayout
+	}
+	if len(p.Fonts) > 0 {
+		fonts := make(map[string]LabwcFont, len(o.Fonts)+len(p.Fonts))
+		for place, f := range o.Fonts {
+			fonts[place] = f
+		}
+		for place, f := range p.Fonts {
+			fonts[place] = fonts[place].overlay(f)
+		}
+		o.Fonts = fonts
+	}
+	return o
+}
+
+func (f LabwcFont) overlay(g LabwcFont) LabwcFont {
+	if g.Name != "" {
+		f.Name = g.Name
+	}
+	if g.Size != 0 {
+		f.Size = g.Size
+	}
+	if g.Slant != "" {
+		f.Slant = g.Slant
+	}
+	if g.Weight != "" {
+		f.Weight = g.Weight
+	}
+	return f
+}
+
+func (o LabwcOptions) Validate() error {
+	if o.CornerRadius != nil && (*o.CornerRadius < 0 || *o.CornerRadius > maxCornerRadius) {
+		return fmt.Errorf("corner radius %d: must be 0-%d", *o.CornerRadius, maxCornerRadius)
+	}
+	if o.TitlebarLayout != "" {
+		if err := ValidateTitlebarLayout(o.TitlebarLayout); err != nil {
+			return err
+		}
+	}
+	for place, f := range o.Fonts {
+		if !oneOf(place, LabwcFontPlaces) {
+			return fmt.Errorf("font place %q: must be one of %s", place, strings.Join(LabwcFontPlaces, ", "))
+		}
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("font %s: %w", place, err)
+		}
+	}
+	return nil
+}
+
+// ValidateTitlebarLayout checks a layout like "icon:iconify,max,close":
+// buttons left of the colon, buttons right of it, each at most once.
+func ValidateTitlebarLayout(layout string) error {
+	if strings.Count(layout, ":") > 1 {
+		return fmt.Errorf("titlebar layout %q: at most one ':'", layout)
+	}
+	seen := map[string]bool{}
+	for _, side := range strings.Split(layout, ":") {
+		if side == "" {
+			continue
+		}
+		for _, b := range strings.Split(side, ",") {
+			b = strings.TrimSpace(b)
+			if !oneOf(b, labwcButtons) {
+				return fmt.Errorf("titlebar layout: unknown button %q (have %s)", b, strings.Join(labwcButtons, ", "))
+			}
+			if seen[b] {
+				return fmt.Errorf("titlebar layout: %q appears twice", b)
+			}
+			seen[b] = true
+		}
+	}
+	return nil
+}
+
+func (f LabwcFont) Validate() error {
+	if f.Size < 0 || f.Size > 200 {
+		return fmt.Errorf("size %d out of range", f.Size)
+	}
+	if f.Slant != "" && !oneOf(f.Slant, labwcFontSlants) {
+		return fmt.Errorf("slant %q: must be one of %s", f.Slant, strings.Join(labwcFontSlants, ", "))
+	}
+	if f.Weight != "" && !oneOf(f.Weight, labwcFontWeights) {
+		return fmt.Errorf("weight %q: must be one of %s", f.Weight, strings.Join(labwcFontWeights, ", "))
+	}
+	return nil
+}
+
+// ParseLabwcFont reads the TUI's "Name Size [weight] [slant]" form, e.g.
+// "Inter Display 10 bold". Everything before the recognised trailing
+// words is the family name.
+func ParseLabwcFont(s string) (LabwcFont, error) {
+	var f LabwcFont
+	words := strings.Fields(s)
+	for len(words) > 0 {
+		w := strings.ToLower(words[len(words)-1])
+		switch {
+		case f.Weight == "" && oneOf(w, labwcFontWeights) && len(words) > 1:
+			f.Weight = w
+		case f.Slant == "" && oneOf(w, labwcFontSlants) && len(words) > 1:
+			f.Slant = w
+		case f.Size == 0 && len(words) > 1:
+			n, err := strconv.Atoi(w)
+			if err != nil {
+				f.Name = strings.Join(words, " ")
+				return f, f.Validate()
+			}
+			f.Size = n
+		default:
+			f.Name = strings.Join(words, " ")
+			return f, f.Validate()
+		}
+		words = words[:len(words)-1]
+	}
+	return f, f.Validate()
+}
+
+func (f LabwcFont) String() string {
+	var parts []string
+	for _, p := range []string{f.Name, sizeString(f.Size), f.Weight, f.Slant} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func sizeString(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func oneOf(s string, allowed []string) bool {
+	for _, a := range allowed {
+		if s == a {
+			return true
+		}
+	}
+	return false
+}
+
+// writeLabwcOptions sets the options under th (the <theme> element),
+// creating elements as needed, and reports each value that changes.
+func writeLabwcOptions(doc *rcDoc, th *rcNode, o LabwcOptions, rep *Report) {
+	set := func(value string, path ...string) {
+		el := th
+		for _, tag := range path {
+			child := el.child(tag)
+			if child == nil {
+				child = doc.add(el, tag)
+			}
+			el = child
+		}
+		if doc.text(el) != value {
+			doc.setText(el, value)
+			rep.did("rc.xml <theme><%s> %s", strings.Join(path, "><"), value)
+		}
+	}
+	if o.CornerRadius != nil {
+		set(strconv.Itoa(*o.CornerRadius), "cornerRadius")
+	}
+	if o.DropShadows != nil {
+		set(yesNo(*o.DropShadows), "dropShadows")
+	}
+	if o.KeepBorder != nil {
+		set(yesNo(*o.KeepBorder), "keepBorder")
+	}
+	if o.TitlebarLayout != "" {
+		set(o.TitlebarLayout, "titlebar", "layout")
+	}
+	for _, place := range LabwcFontPlaces {
+		f, ok := o.Fonts[place]
+		if !ok {
+			continue
+		}
+		font := th.childWithAttr("font", "place", place)
+		if font == nil {
+			font = doc.add(th, "font", xml.Attr{Name: xml.Name{Local: "place"}, Value: place})
+		}
+		fset := func(tag, value string) {
+			if value == "" {
+				return
+			}
+			el := font.child(tag)
+			if el == nil {
+				el = doc.add(font, tag)
+			}
+			if doc.text(el) != value {
+				doc.setText(el, value)
+				rep.did("rc.xml <font place=%q><%s> %s", place, tag, value)
+			}
+		}
+		fset("name", f.Name)
+		fset("size", sizeString(f.Size))
+		fset("slant", f.Slant)
+		fset("weight", f.Weight)
+	}
+}
+
+// Summary is a short description for the TUI and history.
+func (o LabwcOptions) Summary() string {
+	var parts []string
+	if o.CornerRadius != nil {
+		parts = append(parts, fmt.Sprintf("radius %d", *o.CornerRadius))
+	}
+	if o.DropShadows != nil {
+		parts = append(parts, "shadows "+yesNo(*o.DropShadows))
+	}
+	if o.KeepBorder != nil {
+		parts = append(parts, "border "+yesNo(*o.KeepBorder))
+	}
+	if o.TitlebarLayout != "" {
+		parts = append(parts, "layout "+o.TitlebarLayout)
+	}
+	if n := len(o.Fonts); n > 0 {
+		parts = append(parts, fmt.Sprintf("%d font(s)", n))
+	}
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,217 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// rofi, wofi and tofi get the colors fuzzel gets: the kitty palette goes
+// through the fuzzel mapping once and each launcher picks its keys from
+// the result.
+
+// launchersStep recolors the launchers turned on in Options from the kitty
+// palette. Turning one off takes the rofi and wofi blocks out again; tofi
+// keys are left as they are.
+func launchersStep(sel Selections, opts Options, rep *Report) error {
+	if sel.KittyTheme == "" {
+		return nil
+	}
+	var vals map[string]string
+	if opts.Rofi || opts.Wofi || opts.Tofi {
+		colors, err := kittyPalette(sel.KittyTheme)
+		if err != nil {
+			return err
+		}
+		// fuzzel's step reports the same pairs; this one only adjusts.
+		vals = fuzzelColors(colors, opts.Fuzzel, newContrastChecker("launchers", opts.Contrast))
+	}
+
+	block := ""
+	if opts.Rofi {
+		block = rofiColorBlock(sel.KittyTheme, vals)
+	}
+	if err := spliceColorBlock(theme.RofiConfigPath(), block, false, opts, rep); err != nil {
+		return err
+	}
+
+	block = ""
+	if opts.Wofi {
+		block = wofiColorBlock(sel.KittyTheme, vals, wofiOwnsStyle())
+	}
+	if err := spliceColorBlock(theme.WofiStylePath(), block, true, opts, rep); err != nil {
+		return err
+	}
+
+	if opts.Tofi {
+		return updateTofiColors(vals, opts, rep)
+	}
+	return nil
+}
+
+// pick returns the first of the fuzzel keys that has a color.
+func pick(vals map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v := vals[k]; v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// rofiColors are the variables of rofi's default theme, which most themes
+// built on it use too, and the fuzzel keys they come from.
+var rofiColors = []struct {
+	name string
+	from []string
+}{
+	{"background", []string{"background"}},
+	{"foreground", []string{"text"}},
+	{"normal-background", []string{"background"}},
+	{"normal-foreground", []string{"text"}},
+	{"alternate-normal-background", []string{"background"}},
+	{"alternate-normal-foreground", []string{"text"}},
+	{"selected-normal-background", []string{"selection"}},
+	{"selected-normal-foreground", []string{"selection-text"}},
+	{"active-foreground", []string{"match"}},
+	{"border-color", []string{"border"}},
+	{"separatorcolor", []string{"border"}},
+}
+
+// rofiColorBlock sets the variables in a `*` section; at the end of
+// config.rasi it wins over the @theme's own values.
+func rofiColorBlock(kittyTheme string, vals map[string]string) string {
+	lines := []string{colorBlockBegin, "/* from the kitty theme " + kittyTheme + " */", "* {"}
+	for _, c := range rofiColors {
+		if v := pick(vals, c.from...); v != "" {
+			lines = append(lines, "    "+c.name+": #"+v+";")
+		}
+	}
+	lines = append(lines, "}", colorBlockEnd, "")
+	return strings.Join(lines, "\n")
+}
+
+// wofiOwnsStyle reports whether style.css has no rules of the user's own,
+// so the block has to bring wofiStyle; without a stylesheet nothing would
+// use the colors. Once there, it stays as long as the block does.
+func wofiOwnsStyle() bool {
+	b, err := os.ReadFile(theme.WofiStylePath())
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	rest, _ := cutColorBlock(string(b))
+	return strings.TrimSpace(rest) == "" || strings.Contains(colorBlockOf(string(b)), wofiStyle)
+}
+
+// wofiColorBlock defines wofi_* colors for style.css to use and, with
+// withStyle, the rules that use them.
+func wofiColorBlock(kittyTheme string, vals map[string]string, withStyle bool) string {
+	colors := []struct{ name, v string }{
+		{"wofi_bg", pick(vals, "background")},
+		{"wofi_fg", pick(vals, "text")},
+		{"wofi_input", pick(vals, "input", "text")},
+		{"wofi_match", pick(vals, "match")},
+		{"wofi_selected_bg", pick(vals, "selection")},
+		{"wofi_selected_fg", pick(vals, "selection-text")},
+		{"wofi_border", pick(vals, "border")},
+	}
+	lines := []string{colorBlockBegin, "/* from the kitty theme " + kittyTheme + " */"}
+	for _, c := range colors {
+		if c.v != "" {
+			lines = append(lines, "@define-color "+c.name+" "+cssColor(c.v)+";")
+		}
+	}
+	if withStyle {
+		lines = append(lines, "", strings.TrimSuffix(wofiStyle, "\n"))
+	}
+	lines = append(lines, colorBlockEnd, "")
+	return strings.Join(lines, "\n")
+}
+
+// wofiStyle goes in the block below the colors when style.css has nothing else.
+const wofiStyle = `window {
+    background-color: @wofi_bg;
+    border: 2px solid @wofi_border;
+}
+
+#input {
+    color: @wofi_input;
+    background-color: @wofi_bg;
+}
+
+#text {
+    color: @wofi_fg;
+}
+
+#entry:selected {
+    background-color: @wofi_selected_bg;
+}
+
+#entry:selected #text {
+    color: @wofi_selected_fg;
+}
+`
+
+// cssColor turns rrggbbaa into something GTK's CSS parser takes.
+func cssColor(v string) string {
+	if len(v) != 8 || strings.HasSuffix(strings.ToLower(v), "ff") {
+		return "#" + v[:6]
+	}
+	a, err := strconv.ParseUint(v[6:], 16, 8)
+	if err != nil {
+		return "#" + v[:6]
+	}
+	return fmt.Sprintf("alpha(#%s, %.2f)", v[:6], float64(a)/255)
+}
+
+// tofiColors are tofi's color options and the fuzzel keys they come from.
+var tofiColors = []struct {
+	name string
+	from []string
+}{
+	{"background-color", []string{"background"}},
+	{"border-color", []string{"border"}},
+	{"text-color", []string{"text"}},
+	{"prompt-color", []string{"prompt"}},
+	{"placeholder-color", []string{"placeholder"}},
+	{"input-color", []string{"input"}},
+	{"default-result-color", []string{"text"}},
+	{"selection-color", []string{"selection-text"}},
+	{"selection-background", []string{"selection"}},
+	{"selection-match-color", []string{"selection-match"}},
+}
+
+// updateTofiColors sets tofi's color options and leaves every other line
+// of its config alone.
+func updateTofiColors(vals map[string]string, opts Options, rep *Report) error {
+	path := theme.TofiConfigPath()
+	old, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read tofi config: %w", err)
+	}
+	cfg := splitIni(string(old))
+	for _, c := range tofiColors {
+		if v := pick(vals, c.from...); v != "" {
+			cfg.set("", c.name, "#"+v)
+		}
+	}
+	out := cfg.String()
+	if out == string(old) {
+		return nil
+	}
+	rep.did("colors in %s", path)
+	if opts.DryRun {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("write tofi config: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(out), 0o644); err != nil {
+		return fmt.Errorf("write tofi config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// paletteThemeStep writes the Openbox theme a style falls back to when no
+// installed one matches (see theme.PaletteThemeName).
+func paletteThemeStep(sel Selections, opts Options, rep *Report) error {
+	if sel.KittyTheme == "" || sel.OpenboxTheme != theme.PaletteThemeName(sel.KittyTheme) {
+		return nil
+	}
+	_, err := GenerateOpenboxTheme(sel.OpenboxTheme, sel.KittyTheme, opts, rep)
+	return err
+}
+
+// GenerateOpenboxTheme writes a complete openbox-3 theme (themerc and an
+// xbm button set) named name into the user's theme dir, colored from a
+// kitty theme. It refuses to overwrite a theme it didn't generate.
+func GenerateOpenboxTheme(name, kittyTheme string, opts Options, rep *Report) (string, error) {
+	if name == "" || strings.ContainsAny(name, "/\x00") || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid theme name %q", name)
+	}
+	colors, err := kittyPalette(kittyTheme)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(theme.UserThemesDir(), name, "openbox-3")
+	themerc := filepath.Join(dir, "themerc")
+	if old, err := os.ReadFile(themerc); err == nil && !bytes.HasPrefix(old, []byte(themercMarker)) {
+		return "", fmt.Errorf("%s exists and wasn't generated by labwcchanger-tui", themerc)
+	}
+
+	chk := newContrastChecker("openbox theme", opts.Contrast)
+	files := map[string][]byte{"themerc": openboxThemerc(kittyTheme, decorationColors(colors, chk))}
+	chk.report(rep)
+	for button, rows := range xbmButtons {
+		files[button+".xbm"] = xbm(button, rows)
+	}
+	changed := false
+	for f, data := range files {
+		if old, err := os.ReadFile(filepath.Join(dir, f)); err != nil || !bytes.Equal(old, data) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return dir, nil
+	}
+	rep.did("generate Openbox theme %s from %s", name, kittyTheme)
+	if opts.DryRun {
+		return dir, nil
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("generate theme: %w", err)
+	}
+	for f, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, f), data, 0o644); err != nil {
+			return "", fmt.Errorf("generate theme: %w", err)
+		}
+	}
+	return dir, nil
+}
+
+func openboxThemerc(kittyTheme string, d decoration) []byte {
+	lines := []string{
+		themercMarker + " from the kitty theme " + kittyTheme + ".",
+		"# Regenerated on apply; copy it under another name to edit it.",
+		"",
+		"border.width: 1",
+		"padding.width: 4",
+		"padding.height: 4",
+		"window.handle.width: 0",
+		"window.client.padding.width: 0",
+		"window.client.padding.height: 0",
+		"window.label.text.justify: center",
+		"",
+		"window.active.border.color: " + d.accent,
+		"window.inactive.border.color: " + d.inactiveBorder,
+		"window.active.client.color: " + d.activeBg,
+		"window.inactive.client.color: " + d.inactiveBg,
+		"window.active.title.bg: flat solid",
+		"window.active.title.bg.color: " + d.activeBg,
+		"window.inactive.title.bg: flat solid",
+		"window.inactive.title.bg.color: " + d.inactiveBg,
+		"window.active.label.bg: parentrelative",
+		"window.active.label.text.color: " + d.activeFg,
+		"window.inactive.label.bg: parentrelative",
+		"window.inactive.label.text.color: " + d.inactiveFg,
+		"window.active.handle.bg: flat solid",
+		"window.active.handle.bg.color: " + d.activeBg,
+		"window.inactive.handle.bg: flat solid",
+		"window.inactive.handle.bg.color: " + d.inactiveBg,
+		"window.active.grip.bg: flat solid",
+		"window.active.grip.bg.color: " + d.accent,
+		"window.inactive.grip.bg: flat solid",
+		"window.inactive.grip.bg.color: " + d.inactiveBorder,
+		"",
+		"window.active.button.unpressed.bg: parentrelative",
+		"window.active.button.unpressed.image.color: " + d.activeFg,
+		"window.active.button.hover.bg: flat solid",
+		"window.active.button.hover.bg.color: " + d.accent,
+		"window.active.button.hover.image.color: " + d.onAccent,
+		"window.active.button.pressed.bg: flat solid",
+		"window.active.button.pressed.bg.color: " + d.accent,
+		"window.active.button.pressed.image.color: " + d.onAccent,
+		"window.active.button.disabled.bg: parentrelative",
+		"window.active.button.disabled.image.color: " + d.dim,
+		"window.active.button.toggled.bg: parentrelative",
+		"window.active.button.toggled.image.color: " + d.accent,
+		"window.inactive.button.unpressed.bg: parentrelative",
+		"window.inactive.button.unpressed.image.color: " + d.inactiveFg,
+		"window.inactive.button.hover.bg: flat solid",
+		"window.inactive.button.hover.bg.color: " + d.inactiveBorder,
+		"window.inactive.button.hover.image.color: " + d.bg,
+		"window.inactive.button.pressed.bg: flat solid",
+		"window.inactive.button.pressed.bg.color: " + d.inactiveBorder,
+		"window.inactive.button.pressed.image.color: " + d.bg,
+		"window.inactive.button.disabled.bg: parentrelative",
+		"window.inactive.button.disabled.image.color: " + d.dim,
+		"window.inactive.button.toggled.bg: parentrelative",
+		"window.inactive.button.toggled.image.color: " + d.inactiveFg,
+		"",
+		"menu.border.width: 1",
+		"menu.border.color: " + d.accent,
+		"menu.separator.color: " + d.dim,
+		"menu.title.bg: flat solid",
+		"menu.title.bg.color: " + d.activeBg,
+		"menu.title.text.color: " + d.activeFg,
+		"menu.title.text.justify: center",
+		"menu.items.bg: flat solid",
+		"menu.items.bg.color: " + d.bg,
+		"menu.items.text.color: " + d.fg,
+		"menu.items.disabled.text.color: " + d.dim,
+		"menu.items.active.bg: flat solid",
+		"menu.items.active.bg.color: " + d.accent,
+		"menu.items.active.text.color: " + d.onAccent,
+		"",
+		"osd.border.width: 1",
+		"osd.border.color: " + d.accent,
+		"osd.bg: flat solid",
+		"osd.bg.color: " + d.bg,
+		"osd.label.text.color: " + d.fg,
+		"osd.hilight.bg: flat solid",
+		"osd.hilight.bg.color: " + d.accent,
+		"osd.unhilight.bg: flat solid",
+		"osd.unhilight.bg.color: " + d.dim,
+		"",
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// xbmButtons are 8x8 button masks, '#' set. The colors come from themerc.
+var xbmButtons = map[string][]string{
+	"close": {
+		"##....##",
+		"###..###",
+		".######.",
+		"..####..",
+		"..####..",
+		".######.",
+		"###..###",
+		"##....##",
+	},
+	"max": {
+		"########",
+		"########",
+		"#......#",
+		"#......#",
+		"#......#",
+		"#......#",
+		"#......#",
+		"########",
+	},
+	"max_toggled": {
+		"..######",
+		"..#....#",
+		"######.#",
+		"######.#",
+		"#....###",
+		"#....#..",
+		"#....#..",
+		"######..",
+	},
+	"iconify": {
+		"........",
+		"........",
+		"........",
+		"........",
+		"........",
+		"........",
+		"########",
+		"########",
+	},
+	"menu": {
+		"........",
+		"........",
+		"########",
+		".######.",
+		"..####..",
+		"...##...",
+		"........",
+		"........",
+	},
+	"desk": {
+		"##....##",
+		"##....##",
+		"........",
+		"........",
+		"........",
+		"........",
+		"##....##",
+		"##....##",
+	},
+	"desk_toggled": {
+		"........",
+		".##..##.",
+		".##..##.",
+		"........",
+		"........",
+		".##..##.",
+		".##..##.",
+		"........",
+	},
+	"shade": {
+		"########",
+		"########",
+		"........",
+		"........",
+		"........",
+		"........",
+		"........",
+		"........",
+	},
+	"shade_toggled": {
+		"########",
+		"########",
+		"........",
+		"...##...",
+		"..####..",
+		".######.",
+		"........",
+		"........",
+	},
+}
+
+// xbm encodes rows as an X bitmap: each row padded to whole bytes, least
+// significant bit first.
+func xbm(name string, rows []string) []byte {
+	width := len(rows[0])
+	var bits []string
+	for _, row := range rows {
+		for i := 0; i < width; i += 8 {
+			var v byte
+			for j := 0; j < 8 && i+j < width; j++ {
+				if row[i+j] == '#' {
+					v |= 1 << j
+				}
+			}
+			bits = append(bits, fmt.Sprintf("0x%02x", v))
+		}
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "#define %s_width %d\n", name, width)
+	fmt.Fprintf(&b, "#define %s_height %d\n", name, len(rows))
+	fmt.Fprintf(&b, "static unsigned char %s_bits[] = {\n   %s };\n", name, strings.Join(bits, ", "))
+	return []byte(b.String())
+}
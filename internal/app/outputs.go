@@ -0,0 +1,171 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Output is one connected monitor as the compositor names it (e.g. "DP-1").
+type Output struct {
+	Name   string `json:"name"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+// OutputSource discovers the connected outputs.
+type OutputSource interface {
+	Outputs() ([]Output, error)
+}
+
+// Outputs is what the TUI uses. Tests swap in StaticOutputs.
+var Outputs OutputSource = autoOutputs{}
+
+// StaticOutputs is a fixed list, for tests and fakes.
+type StaticOutputs []Output
+
+func (s StaticOutputs) Outputs() ([]Output, error) { return s, nil }
+
+// autoOutputs asks wlr-randr, then swww, whichever answers first.
+type autoOutputs struct{}
+
+func (autoOutputs) Outputs() ([]Output, error) {
+	if outs, err := wlrRandrOutputs(); err == nil && len(outs) > 0 {
+		return outs, nil
+	}
+	if outs, err := swwwQueryOutputs(); err == nil && len(outs) > 0 {
+		return outs, nil
+	}
+	return nil, errors.New("no outputs found (need wlr-randr or a running swww-daemon)")
+}
+
+func commandOutput(name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func wlrRandrOutputs() ([]Output, error) {
+	b, err := commandOutput("wlr-randr", "--json")
+	if err == nil {
+		return parseWlrRandrJSON(b)
+	}
+	// Older wlr-randr has no --json.
+	b, err = commandOutput("wlr-randr")
+	if err != nil {
+		return nil, err
+	}
+	return parseWlrRandrText(b), nil
+}
+
+func parseWlrRandrJSON(b []byte) ([]Output, error) {
+	var raw []struct {
+		Name    string `json:"name"`
+		Enabled bool   `json:"enabled"`
+		Modes   []struct {
+			Width   int  `json:"width"`
+			Height  int  `json:"height"`
+			Current bool `json:"current"`
+		} `json:"modes"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	var out []Output
+	for _, r := range raw {
+		if !r.Enabled {
+			continue
+		}
+		o := Output{Name: r.Name}
+		for _, m := range r.Modes {
+			if m.Current {
+				o.Width, o.Height = m.Width, m.Height
+			}
+		}
+		out = append(out, o)
+	}
+	return sortOutputs(out), nil
+}
+
+var wlrModeRe = regexp.MustCompile(`^\s+(\d+)x(\d+) px.*current`)
+
+// parseWlrRandrText reads the plain format: an unindented line per output
+// followed by indented properties, including a mode list.
+func parseWlrRandrText(b []byte) []Output {
+	var out []Output
+	var cur *Output
+	enabled := true
+	flush := func() {
+		if cur != nil && enabled {
+			out = append(out, *cur)
+		}
+	}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		line := s.Text()
+		if line == "" {
+			continue
+		}
+		if line[0] != ' ' && line[0] != '\t' {
+			flush()
+			name, _, _ := strings.Cut(line, " ")
+			cur = &Output{Name: name}
+			enabled = true
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		if strings.TrimSpace(line) == "Enabled: no" {
+			enabled = false
+		}
+		if m := wlrModeRe.FindStringSubmatch(line); m != nil {
+			cur.Width, _ = strconv.Atoi(m[1])
+			cur.Height, _ = strconv.Atoi(m[2])
+		}
+	}
+	flush()
+	return sortOutputs(out)
+}
+
+func swwwQueryOutputs() ([]Output, error) {
+	b, err := commandOutput("swww", "query")
+	if err != nil {
+		return nil, err
+	}
+	return parseSwwwQuery(b), nil
+}
+
+// Lines look like "DP-1: 2560x1440, scale: 1, currently displaying: ...",
+// with some swww versions prefixing ": " before the name.
+var swwwQueryRe = regexp.MustCompile(`^:?\s*([^:\s]+):\s*(\d+)x(\d+)`)
+
+func parseSwwwQuery(b []byte) []Output {
+	var out []Output
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		m := swwwQueryRe.FindStringSubmatch(s.Text())
+		if m == nil {
+			continue
+		}
+		w, _ := strconv.Atoi(m[2])
+		h, _ := strconv.Atoi(m[3])
+		out = append(out, Output{Name: m[1], Width: w, Height: h})
+	}
+	return sortOutputs(out)
+}
+
+func sortOutputs(outs []Output) []Output {
+	sort.Slice(outs, func(i, j int) bool { return outs[i].Name < outs[j].Name })
+	return outs
+}
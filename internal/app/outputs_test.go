@@ -0,0 +1,112 @@
+package app
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseWlrRandrJSON(t *testing.T) {
+	in := `[
+  {"name": "HDMI-A-1", "description": "Acme 24", "enabled": true,
+   "modes": [
+     {"width": 1920, "height": 1080, "refresh": 60.0, "preferred": true, "current": false},
+     {"width": 1280, "height": 720, "refresh": 60.0, "preferred": false, "current": true}
+   ]},
+  {"name": "DP-1", "description": "Dell U2719D", "enabled": true,
+   "modes": [{"width": 2560, "height": 1440, "refresh": 59.951, "preferred": true, "current": true}],
+   "position": {"x": 0, "y": 0}, "scale": 1.0},
+  {"name": "DP-2", "enabled": false,
+   "modes": [{"width": 1920, "height": 1200, "current": false}]},
+  {"name": "eDP-1", "enabled": true, "modes": []}
+]`
+	got, err := parseWlrRandrJSON([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Output{
+		{Name: "DP-1", Width: 2560, Height: 1440},
+		{Name: "HDMI-A-1", Width: 1280, Height: 720},
+		{Name: "eDP-1"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if _, err := parseWlrRandrJSON([]byte("DP-1 \"Dell\"\n")); err == nil {
+		t.Error("plain text output parsed as JSON")
+	}
+}
+
+func TestParseWlrRandrText(t *testing.T) {
+	in := `eDP-1 "BOE 0x0BCA (eDP-1)"
+  Make: BOE
+  Enabled: yes
+  Modes:
+    2256x1504 px, 59.999001 Hz (preferred, current)
+    1920x1200 px, 59.999001 Hz
+  Position: 0,0
+DP-2 "Unknown (DP-2)"
+  Enabled: no
+  Modes:
+    1920x1080 px, 60.000000 Hz (preferred)
+DP-1 "Dell Inc. DELL U2719D ABC123 (DP-1)"
+  Enabled: yes
+  Modes:
+    2560x1440 px, 59.951000 Hz (preferred)
+    1920x1080 px, 60.000000 Hz (current)
+	Scale: 1.000000
+`
+	want := []Output{
+		{Name: "DP-1", Width: 1920, Height: 1080},
+		{Name: "eDP-1", Width: 2256, Height: 1504},
+	}
+	if got := parseWlrRandrText([]byte(in)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if got := parseWlrRandrText(nil); got != nil {
+		t.Errorf("empty input: got %+v", got)
+	}
+}
+
+func TestParseSwwwQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []Output
+	}{
+		{
+			"plain",
+			"HDMI-A-1: 1920x1080, scale: 1, currently displaying: image: /home/u/walls/a.png\n" +
+				"DP-1: 2560x1440, scale: 2, currently displaying: color: 000000\n",
+			[]Output{{"DP-1", 2560, 1440}, {"HDMI-A-1", 1920, 1080}},
+		},
+		{
+			"colon prefix",
+			": DP-1: 3840x2160, scale: 1.5, currently displaying: image: /tmp/x.jpg\n",
+			[]Output{{"DP-1", 3840, 2160}},
+		},
+		{
+			"noise",
+			"Error: failed to connect to socket\n\n",
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseSwwwQuery([]byte(tt.in)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStaticOutputs(t *testing.T) {
+	saved := Outputs
+	defer func() { Outputs = saved }()
+	want := []Output{{"DP-1", 2560, 1440}, {"HDMI-A-1", 1920, 1080}}
+	Outputs = StaticOutputs(want)
+	got, err := Outputs.Outputs()
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, %v; want %+v", got, err, want)
+	}
+}
@@ -0,0 +1,152 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// Live preview applies one category at a time while the cursor moves and
+// puts everything back afterwards.
+
+// PreviewCategories are the categories Preview accepts.
+var PreviewCategories = []string{"gtk", "labwc", "kitty", "walls"}
+
+// Preview runs the preview steps of Apply for a single category, with
+// every other selection blanked so nothing else is touched.
+func Preview(category string, sel Selections, opts Options) (Report, error) {
+	var rep Report
+	only := sel.Only(category)
+	ran := false
+	for _, st := range steps {
+		if !st.preview || !st.handles(category) {
+			continue
+		}
+		if err := st.run(only, opts, &rep); err != nil {
+			return rep, err
+		}
+		ran = true
+	}
+	if !ran {
+		return rep, fmt.Errorf("no preview for %q", category)
+	}
+	return rep, nil
+}
+
+// Only keeps the selection of one category and clears the rest.
+func (s Selections) Only(category string) Selections {
+	var out Selections
+	switch category {
+	case "gtk":
+		out.GtkTheme = s.GtkTheme
+	case "icons":
+		out.IconTheme = s.IconTheme
+	case "labwc", "openbox":
+		out.OpenboxTheme = s.OpenboxTheme
+		out.Labwc = s.Labwc
+	case "kitty":
+		out.KittyTheme = s.KittyTheme
+	case "walls":
+		out.Wallpaper = s.Wallpaper
+		out.Wallpapers = s.Wallpapers
+	}
+	return out
+}
+
+// PreviewSnapshot is the state Restore returns to: the settings that are
+// re-applied by name, and verbatim copies of the files previews rewrite.
+type PreviewSnapshot struct {
+	sel   Selections
+	files map[string][]byte // nil: the file didn't exist
+}
+
+// previewFiles are the files each category's preview may change.
+func previewFiles(category string) []string {
+	switch category {
+	case "gtk":
+		return []string{theme.Gtk4SettingsPath()}
+	case "labwc":
+		return []string{theme.LabwcRcPath()}
+	case "kitty":
+		return []string{theme.KittyConfPath(), theme.KittyCurrentThemePath(), theme.FuzzelIniPath(), theme.LabwcThemercOverridePath(),
+			theme.RofiConfigPath(), theme.WofiStylePath(), theme.TofiConfigPath()}
+	}
+	return nil
+}
+
+// CapturePreview records the state before previewing. known fills in what
+// can't be read back from the system, such as the wallpaper.
+func CapturePreview(known Selections) PreviewSnapshot {
+	sel := Selections{GtkTheme: known.GtkTheme, Wallpaper: known.Wallpaper, Wallpapers: known.Wallpapers}
+	if cur := theme.LoadCurrentSettings().GtkTheme; cur != "" {
+		sel.GtkTheme = cur
+	}
+	snap := PreviewSnapshot{sel: sel, files: map[string][]byte{}}
+	for _, c := range PreviewCategories {
+		for _, f := range previewFiles(c) {
+			b, err := os.ReadFile(f)
+			if err != nil {
+				b = nil
+			}
+			snap.files[f] = b
+		}
+	}
+	return snap
+}
+
+// Restore undoes the previews of the given categories. It keeps going
+// after a failure so one broken category doesn't strand the others.
+func (p PreviewSnapshot) Restore(categories []string, opts Options) (Report, error) {
+	var rep Report
+	var errs []error
+	for _, c := range categories {
+		if c == "walls" && p.sel.Wallpaper == "" && len(p.sel.Wallpapers) == 0 {
+			rep.warn("wallpaper before the preview is unknown, left as previewed")
+			continue
+		}
+		// rc.xml and the kitty files are restored verbatim below; gsettings
+		// and the wallpaper have to be set again.
+		if c == "gtk" || c == "walls" {
+			r, err := Preview(c, p.sel, opts)
+			rep.Actions = append(rep.Actions, r.Actions...)
+			rep.Warnings = append(rep.Warnings, r.Warnings...)
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}
+		for _, f := range previewFiles(c) {
+			if err := restoreFile(f, p.files[f]); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		switch c {
+		case "labwc":
+			_ = reloadLabwc(p.sel, opts, &rep)
+		case "kitty":
+			// SIGUSR1 makes kitty re-read kitty.conf and its includes.
+			for _, pid := range pidsOf("kitty") {
+				_ = syscall.Kill(pid, syscall.SIGUSR1)
+			}
+			rep.did("kitty config restored")
+			// themerc-override follows the kitty theme.
+			_ = reloadLabwc(p.sel, opts, &rep)
+		}
+	}
+	return rep, errors.Join(errs...)
+}
+
+func restoreFile(path string, data []byte) error {
+	if data == nil {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("restore %s: %w", path, err)
+		}
+		return nil
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("restore %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// pidsOf returns the PIDs of processes whose comm is exactly name.
+func pidsOf(name string) []int {
+	matches, _ := filepath.Glob("/proc/[0-9]*/comm")
+	var out []int
+	for _, m := range matches {
+		b, err := os.ReadFile(m)
+		if err != nil || strings.TrimSpace(string(b)) != name {
+			continue
+		}
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(m)))
+		if err == nil && pid != os.Getpid() {
+			out = append(out, pid)
+		}
+	}
+	return out
+}
+
+func processRunning(name string) bool {
+	return len(pidsOf(name)) > 0
+}
+
+// respawn starts a fresh long-running process and only then stops the old
+// instances, so tools like swaybg never leave the screen bare.
+func respawn(comm, name string, args ...string) error {
+	old := pidsOf(comm)
+	cmd := exec.Command(name, args...)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() { _ = cmd.Wait() }()
+	for _, pid := range old {
+		_ = syscall.Kill(pid, syscall.SIGTERM)
+	}
+	return nil
+}
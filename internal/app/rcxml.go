@@ -0,0 +1,383 @@
+package app
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// rcDoc edits rc.xml in place. Text that changes is replaced where it
+// stands and new elements are spliced in with the indentation around
+// them; every other byte (comments, CDATA, attribute order, hand
+// formatting) is written back untouched.
+type rcDoc struct {
+	src   []byte
+	root  *rcNode
+	edits []rcEdit
+	dirty bool
+}
+
+// rcEdit replaces src[at:end] with text.
+type rcEdit struct {
+	at, end int
+	text    string
+}
+
+type rcNode struct {
+	tag      string // local name, for matching
+	raw      string // name as written, prefix included
+	attrs    []xml.Attr
+	parent   *rcNode
+	children []*rcNode
+
+	// Elements read from src carry their byte offsets.
+	orig                     bool
+	start, end               int // '<' of the start tag, just past the end tag
+	contentStart, contentEnd int
+	selfClosing              bool
+	texts                    []rcText // non-blank text directly inside
+
+	// Elements added by an edit carry their text here.
+	text string
+}
+
+type rcText struct {
+	start, end int
+	value      string
+	cdata      bool
+}
+
+// newRcXml is what a missing rc.xml starts from when it may be created.
+const newRcXml = "<?xml version=\"1.0\"?>\n<labwc_config>\n</labwc_config>\n"
+
+func parseRc(src []byte) (*rcDoc, error) {
+	d := &rcDoc{src: src}
+	dec := xml.NewDecoder(bytes.NewReader(src))
+	var stack []*rcNode
+	for {
+		before := int(dec.InputOffset())
+		tok, err := dec.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		after := int(dec.InputOffset())
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &rcNode{
+				tag:          t.Name.Local,
+				raw:          rawName(t.Name),
+				attrs:        append([]xml.Attr(nil), t.Attr...),
+				orig:         true,
+				start:        before,
+				contentStart: after,
+				selfClosing:  after >= 2 && string(src[after-2:after]) == "/>",
+			}
+			if len(stack) > 0 {
+				n.parent = stack[len(stack)-1]
+				n.parent.children = append(n.parent.children, n)
+			} else if d.root == nil {
+				d.root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("unexpected </%s>", t.Name.Local)
+			}
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			n.contentEnd, n.end = before, after
+			if n.selfClosing {
+				n.contentEnd = n.contentStart
+			}
+		case xml.CharData:
+			if len(stack) == 0 || strings.TrimSpace(string(t)) == "" {
+				continue
+			}
+			n := stack[len(stack)-1]
+			n.texts = append(n.texts, rcText{
+				start: before,
+				end:   after,
+				value: string(t),
+				cdata: bytes.HasPrefix(src[before:after], []byte("<![CDATA[")),
+			})
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("<%s> is never closed", stack[len(stack)-1].raw)
+	}
+	if d.root == nil {
+		return nil, errors.New("no root element")
+	}
+	return d, nil
+}
+
+func rawName(n xml.Name) string {
+	if n.Space != "" {
+		return n.Space + ":" + n.Local
+	}
+	return n.Local
+}
+
+// find returns the first element, in document order, named tag whose
+// parent is named parent.
+func (d *rcDoc) find(parent, tag string) *rcNode {
+	var walk func(n *rcNode) *rcNode
+	walk = func(n *rcNode) *rcNode {
+		for _, c := range n.children {
+			if c.tag == tag && n.tag == parent {
+				return c
+			}
+			if found := walk(c); found != nil {
+				return found
+			}
+		}
+		return nil
+	}
+	return walk(d.root)
+}
+
+func (n *rcNode) child(tag string) *rcNode {
+	for _, c := range n.children {
+		if c.tag == tag {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *rcNode) childWithAttr(tag, attr, value string) *rcNode {
+	for _, c := range n.children {
+		if c.tag == tag && c.attr(attr) == value {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *rcNode) attr(name string) string {
+	for _, a := range n.attrs {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// add appends a new child element, written out by bytes.
+func (d *rcDoc) add(parent *rcNode, tag string, attrs ...xml.Attr) *rcNode {
+	n := &rcNode{tag: tag, raw: tag, attrs: attrs, parent: parent}
+	parent.children = append(parent.children, n)
+	d.dirty = true
+	return n
+}
+
+func (d *rcDoc) text(n *rcNode) string {
+	if !n.orig {
+		return n.text
+	}
+	var b strings.Builder
+	for _, t := range n.texts {
+		b.WriteString(t.value)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// setText replaces the element's text, keeping the whitespace around it
+// and writing CDATA where the file used CDATA.
+func (d *rcDoc) setText(n *rcNode, value string) {
+	d.dirty = true
+	if !n.orig {
+		n.text = value
+		return
+	}
+	switch {
+	case len(n.texts) > 0:
+		for i, t := range n.texts {
+			repl := ""
+			if i == 0 {
+				repl = d.replaceText(t, value)
+			}
+			d.edits = append(d.edits, rcEdit{at: t.start, end: t.end, text: repl})
+		}
+	case n.selfClosing:
+		d.edits = append(d.edits, rcEdit{
+			at:   n.contentStart - 2,
+			end:  n.contentStart,
+			text: ">" + escapeText(value) + "</" + n.raw + ">",
+		})
+	case len(n.children) == 0:
+		d.edits = append(d.edits, rcEdit{at: n.contentStart, end: n.contentEnd, text: escapeText(value)})
+	default:
+		d.edits = append(d.edits, rcEdit{at: n.contentStart, end: n.contentStart, text: escapeText(value)})
+	}
+}
+
+func (d *rcDoc) replaceText(t rcText, value string) string {
+	if t.cdata && !strings.Contains(value, "]]>") {
+		return "<![CDATA[" + value + "]]>"
+	}
+	raw := string(d.src[t.start:t.end])
+	if t.cdata {
+		return escapeText(value)
+	}
+	lead := len(raw) - len(strings.TrimLeft(raw, " \t\r\n"))
+	trail := len(raw) - len(strings.TrimRight(raw, " \t\r\n"))
+	return raw[:lead] + escapeText(value) + raw[len(raw)-trail:]
+}
+
+// bytes renders src with the edits and new elements applied.
+func (d *rcDoc) bytes() []byte {
+	edits := append([]rcEdit(nil), d.edits...)
+	var walk func(n *rcNode)
+	walk = func(n *rcNode) {
+		if !n.orig {
+			return
+		}
+		if e, ok := d.insertion(n); ok {
+			edits = append(edits, e)
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	walk(d.root)
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].at < edits[j].at })
+
+	var out bytes.Buffer
+	pos := 0
+	for _, e := range edits {
+		out.Write(d.src[pos:e.at])
+		out.WriteString(e.text)
+		pos = e.end
+	}
+	out.Write(d.src[pos:])
+	return out.Bytes()
+}
+
+// insertion writes out n's new children after its last existing content,
+// one per line at the indentation of their siblings, or inline when the
+// siblings share a line.
+func (d *rcDoc) insertion(n *rcNode) (rcEdit, bool) {
+	var added []*rcNode
+	var last *rcNode
+	for _, c := range n.children {
+		if c.orig {
+			last = c
+		} else {
+			added = append(added, c)
+		}
+	}
+	if len(added) == 0 {
+		return rcEdit{}, false
+	}
+	indent := d.lineIndent(n.start)
+	childIndent := indent + d.indentUnit()
+	inline := false
+	if last != nil {
+		if ci, ok := d.ownLine(last.start); ok {
+			childIndent = ci
+		} else {
+			inline = true
+		}
+	}
+
+	var b strings.Builder
+	for _, c := range added {
+		if !inline {
+			b.WriteString("\n" + childIndent)
+		}
+		b.WriteString(d.serialize(c, childIndent, inline))
+	}
+
+	if n.selfClosing {
+		if !inline {
+			b.WriteString("\n" + indent)
+		}
+		return rcEdit{at: n.contentStart - 2, end: n.contentStart, text: ">" + b.String() + "</" + n.raw + ">"}, true
+	}
+	at := n.contentEnd
+	for at > n.contentStart && isXMLSpace(d.src[at-1]) {
+		at--
+	}
+	end := at
+	if at == n.contentStart && !inline {
+		// Nothing but whitespace inside: close on a line of its own.
+		end = n.contentEnd
+		b.WriteString("\n" + indent)
+	}
+	return rcEdit{at: at, end: end, text: b.String()}, true
+}
+
+// serialize writes a new element; inline keeps it on one line.
+func (d *rcDoc) serialize(n *rcNode, indent string, inline bool) string {
+	var b strings.Builder
+	b.WriteString("<" + n.raw)
+	for _, a := range n.attrs {
+		b.WriteString(" " + rawName(a.Name) + `="` + escapeAttr(a.Value) + `"`)
+	}
+	b.WriteString(">")
+	switch {
+	case len(n.children) == 0:
+		b.WriteString(escapeText(n.text))
+	case inline:
+		for _, c := range n.children {
+			b.WriteString(d.serialize(c, "", true))
+		}
+	default:
+		inner := indent + d.indentUnit()
+		for _, c := range n.children {
+			b.WriteString("\n" + inner + d.serialize(c, inner, false))
+		}
+		b.WriteString("\n" + indent)
+	}
+	b.WriteString("</" + n.raw + ">")
+	return b.String()
+}
+
+// ownLine returns the indentation before pos when nothing else precedes
+// it on its line.
+func (d *rcDoc) ownLine(pos int) (string, bool) {
+	i := pos
+	for i > 0 && (d.src[i-1] == ' ' || d.src[i-1] == '\t') {
+		i--
+	}
+	if i > 0 && d.src[i-1] != '\n' {
+		return "", false
+	}
+	return string(d.src[i:pos]), true
+}
+
+func (d *rcDoc) lineIndent(pos int) string {
+	ind, _ := d.ownLine(pos)
+	return ind
+}
+
+// indentUnit guesses one level of indentation from the first element
+// that sits on its own line below the root, defaulting to two spaces.
+func (d *rcDoc) indentUnit() string {
+	for _, c := range d.root.children {
+		if ind, ok := d.ownLine(c.start); ok && ind != "" {
+			return ind
+		}
+	}
+	return "  "
+}
+
+func isXMLSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\r' || b == '\n'
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;")
+)
+
+func escapeText(s string) string { return textEscaper.Replace(s) }
+func escapeAttr(s string) string { return attrEscaper.Replace(s) }
@@ -0,0 +1,177 @@
+package app
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the .golden files")
+
+func intp(i int) *int    { return &i }
+func boolp(b bool) *bool { return &b }
+
+// Each case edits testdata/rcxml/<in>.xml and compares the result with
+// testdata/rcxml/<name>.golden.
+var rcXmlTests = []struct {
+	name, in string
+	sel      Selections
+	opts     Options
+	warns    int
+}{
+	{
+		name: "comments", in: "comments",
+		sel: Selections{OpenboxTheme: "Bear2", IconTheme: "Adwaita"},
+	},
+	{
+		name: "cdata", in: "cdata",
+		sel: Selections{OpenboxTheme: "New & Shiny", IconTheme: "Adwaita"},
+	},
+	{
+		name: "selfclosing", in: "selfclosing",
+		sel:  Selections{OpenboxTheme: "Numix", IconTheme: "Papirus", Labwc: &LabwcOptions{DropShadows: boolp(true)}},
+		opts: Options{CreateMissing: true},
+	},
+	{
+		name: "tabs", in: "tabs",
+		sel: Selections{IconTheme: "Papirus", Labwc: &LabwcOptions{
+			CornerRadius: intp(8),
+			Fonts: map[string]LabwcFont{
+				"ActiveWindow": {Size: 11, Weight: "bold"},
+				"MenuItem":     {Name: "Inter", Size: 10},
+			},
+		}},
+	},
+	{
+		name: "inline", in: "inline",
+		sel: Selections{OpenboxTheme: "Bear2", Labwc: &LabwcOptions{KeepBorder: boolp(false), TitlebarLayout: "icon:iconify,max,close"}},
+	},
+	{
+		name: "attrs", in: "attrs",
+		sel: Selections{Labwc: &LabwcOptions{Fonts: map[string]LabwcFont{
+			"MenuItem":     {Name: "Inter", Slant: "italic"},
+			"ActiveWindow": {Size: 12},
+		}}},
+	},
+	{
+		name: "notheme", in: "notheme",
+		sel:  Selections{OpenboxTheme: "Numix", IconTheme: "Papirus"},
+		opts: Options{CreateMissing: true},
+	},
+	{
+		// Abridged from labwc's docs/rc.xml.all, which the distros ship
+		// as the reference config and users copy whole.
+		name: "labwc-all", in: "labwc-all",
+		sel: Selections{OpenboxTheme: "Nightmare", IconTheme: "Papirus-Dark", Labwc: &LabwcOptions{
+			CornerRadius:   intp(4),
+			DropShadows:    boolp(true),
+			TitlebarLayout: "menu:iconify,max,close",
+			Fonts: map[string]LabwcFont{
+				"ActiveWindow": {Name: "Inter", Size: 11, Weight: "bold"},
+				"MenuItem":     {Size: 12},
+			},
+		}},
+	},
+	{
+		// Without create_missing nothing changes and each value warns.
+		name: "notheme-skip", in: "notheme",
+		sel:   Selections{OpenboxTheme: "Numix", IconTheme: "Papirus"},
+		warns: 2,
+	},
+}
+
+func TestEditRcXml(t *testing.T) {
+	for _, tt := range rcXmlTests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := os.ReadFile(filepath.Join("testdata", "rcxml", tt.in+".xml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var rep Report
+			doc, err := editRcXml(src, tt.sel, tt.opts, &rep)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rep.Warnings) != tt.warns {
+				t.Errorf("warnings = %q, want %d", rep.Warnings, tt.warns)
+			}
+			got := doc.bytes()
+
+			golden := filepath.Join("testdata", "rcxml", tt.name+".golden")
+			if tt.warns > 0 {
+				if doc.dirty || !bytes.Equal(got, src) {
+					t.Errorf("skipped edit changed the file:\n%s", got)
+				}
+				return
+			}
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+			if _, err := parseRc(got); err != nil {
+				t.Errorf("result doesn't parse: %v", err)
+			}
+
+			// Applying the same selection again changes nothing.
+			var again Report
+			doc, err = editRcXml(got, tt.sel, tt.opts, &again)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.dirty || len(again.Actions) > 0 {
+				t.Errorf("second edit not a no-op: %q", again.Actions)
+			}
+		})
+	}
+}
+
+// An unchanged document is written back byte for byte.
+func TestRcXmlRoundTrip(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "rcxml", "*.xml"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no testdata: %v", err)
+	}
+	for _, f := range append(files, "") {
+		name := filepath.Base(f)
+		src := []byte(newRcXml)
+		if f != "" {
+			if src, err = os.ReadFile(f); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			name = "newRcXml"
+		}
+		t.Run(name, func(t *testing.T) {
+			doc, err := parseRc(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := doc.bytes(); !bytes.Equal(got, src) {
+				t.Errorf("round trip changed the document:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestParseRcErrors(t *testing.T) {
+	for _, src := range []string{
+		"",
+		"<labwc_config><theme></labwc_config>",
+		"<labwc_config></theme></labwc_config>",
+		"<labwc_config><theme>",
+	} {
+		if _, err := parseRc([]byte(src)); err == nil {
+			t.Errorf("parseRc(%q) succeeded", src)
+		}
+	}
+}
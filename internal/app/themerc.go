@@ -0,0 +1,160 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// themercMarker starts every themerc-override, generated themerc and
+// fuzzel.ini header we write, so we only ever replace or remove our own.
+const themercMarker = "# Generated by labwcchanger-tui"
+
+// themercStep writes ~/.config/labwc/themerc-override from the kitty
+// palette when Options.ThemercOverride is on, and removes a generated
+// one when it's off or when switching to a regular Openbox theme.
+func themercStep(sel Selections, opts Options, rep *Report) error {
+	path := theme.LabwcThemercOverridePath()
+	old, err := os.ReadFile(path)
+	exists := err == nil
+	ours := exists && bytes.HasPrefix(old, []byte(themercMarker))
+
+	if !opts.ThemercOverride || regularOpenboxTheme(sel) {
+		if !ours {
+			return nil
+		}
+		rep.did("remove %s", path)
+		if opts.DryRun {
+			return nil
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove themerc-override: %w", err)
+		}
+		return nil
+	}
+	if sel.KittyTheme == "" {
+		return nil
+	}
+	if exists && !ours {
+		rep.warn("%s wasn't written by labwcchanger-tui, left alone", path)
+		return nil
+	}
+
+	colors, err := kittyPalette(sel.KittyTheme)
+	if err != nil {
+		return err
+	}
+	chk := newContrastChecker("themerc-override", opts.Contrast)
+	out := themercOverride(sel.KittyTheme, decorationColors(colors, chk))
+	chk.report(rep)
+	if bytes.Equal(old, out) {
+		return nil
+	}
+	rep.did("themerc-override from %s", sel.KittyTheme)
+	if opts.DryRun {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("write themerc-override: %w", err)
+	}
+	if err := os.WriteFile(path, out, 0o644); err != nil {
+		return fmt.Errorf("write themerc-override: %w", err)
+	}
+	return nil
+}
+
+// regularOpenboxTheme reports whether sel picks an installed Openbox theme
+// that labwcchanger-tui didn't generate, whose own colors should show.
+func regularOpenboxTheme(sel Selections) bool {
+	if sel.OpenboxTheme == "" || sel.OpenboxTheme == theme.PaletteThemeName(sel.KittyTheme) {
+		return false
+	}
+	found := false
+	for _, dir := range theme.ThemeDirs() {
+		b, err := os.ReadFile(filepath.Join(dir, sel.OpenboxTheme, "openbox-3", "themerc"))
+		if err != nil {
+			continue
+		}
+		if bytes.HasPrefix(b, []byte(themercMarker)) {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// decoration is a kitty palette mapped onto window decorations. The
+// title bar follows kitty's tab bar colors where the theme has them, the
+// borders follow kitty's window borders.
+type decoration struct {
+	bg, fg, accent, dim    string
+	activeBg, activeFg     string
+	inactiveBg, inactiveFg string
+	inactiveBorder         string
+	onAccent               string // text on accent, bg unless adjusted
+}
+
+// decorationColors maps the palette and has chk check (and maybe adjust)
+// each text color against what it is drawn on.
+func decorationColors(colors map[string]string, chk *contrastChecker) decoration {
+	c := func(keys ...string) string {
+		for _, k := range keys {
+			if v := colors[k]; v != "" {
+				return "#" + strings.ToLower(v)
+			}
+		}
+		return ""
+	}
+	var d decoration
+	d.bg = firstNonEmpty(c("background"), "#000000")
+	d.fg = firstNonEmpty(c("foreground"), "#ffffff")
+	d.accent = firstNonEmpty(c("active_border_color", "color4", "color12"), d.fg)
+	d.dim = firstNonEmpty(c("color8"), d.fg)
+	d.activeBg = firstNonEmpty(c("active_tab_background", "selection_background"), d.bg)
+	d.activeFg = firstNonEmpty(c("active_tab_foreground", "selection_foreground"), d.fg)
+	d.inactiveBg = firstNonEmpty(c("inactive_tab_background"), d.bg)
+	d.inactiveFg = firstNonEmpty(c("inactive_tab_foreground"), d.dim)
+	d.inactiveBorder = firstNonEmpty(c("inactive_border_color"), d.dim)
+
+	d.fg = chk.fix("text", d.fg, "background", d.bg)
+	d.activeFg = chk.fix("active title", d.activeFg, "active title bg", d.activeBg)
+	d.inactiveFg = chk.fix("inactive title", d.inactiveFg, "inactive title bg", d.inactiveBg)
+	d.onAccent = chk.fix("accent text", d.bg, "accent", d.accent)
+	return d
+}
+
+// themercOverride sets labwc's window, button, menu and OSD colors.
+func themercOverride(name string, d decoration) []byte {
+	lines := []string{
+		themercMarker + " from the kitty theme " + name + ".",
+		"# It is replaced on every apply and removed when themerc_override is turned off.",
+		"",
+		"window.active.border.color: " + d.accent,
+		"window.inactive.border.color: " + d.inactiveBorder,
+		"window.active.title.bg.color: " + d.activeBg,
+		"window.inactive.title.bg.color: " + d.inactiveBg,
+		"window.active.label.text.color: " + d.activeFg,
+		"window.inactive.label.text.color: " + d.inactiveFg,
+		"window.active.button.unpressed.image.color: " + d.activeFg,
+		"window.inactive.button.unpressed.image.color: " + d.inactiveFg,
+		"",
+		"menu.border.color: " + d.accent,
+		"menu.items.bg.color: " + d.bg,
+		"menu.items.text.color: " + d.fg,
+		"menu.items.active.bg.color: " + d.accent,
+		"menu.items.active.text.color: " + d.onAccent,
+		"menu.separator.color: " + d.dim,
+		"menu.title.bg.color: " + d.activeBg,
+		"menu.title.text.color: " + d.activeFg,
+		"",
+		"osd.bg.color: " + d.bg,
+		"osd.border.color: " + d.accent,
+		"osd.label.text.color: " + d.fg,
+		"",
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,291 @@
+package app
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// WallpaperBackend sets the desktop background through one wallpaper daemon.
+type WallpaperBackend interface {
+	Name() string
+	// Running reports whether the backend's daemon is up, for auto-detection.
+	Running() bool
+	// Formats lists the extensions it can show beyond theme.DefaultWallpaperExts.
+	Formats() []string
+	Set(walls OutputWallpapers) error
+}
+
+// OutputWallpapers maps output name to image path. The "" key is the
+// default for every output without its own entry.
+type OutputWallpapers map[string]string
+
+// outputs returns the named outputs in a stable order, without "".
+func (w OutputWallpapers) outputs() []string {
+	var out []string
+	for name := range w {
+		if name != "" {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+type WallpaperOptions struct {
+	// Backend is "auto" (default), "swww", "swaybg", "wbg", "hyprpaper" or "command".
+	Backend string `json:"backend,omitempty"`
+	// Command is used by the "command" backend; {path} is replaced by the
+	// image and {output} by the output name (empty for all outputs).
+	Command string      `json:"command,omitempty"`
+	Swww    SwwwOptions `json:"swww,omitempty"`
+	// Mode is passed to swaybg -m (default "fill").
+	Mode string `json:"mode,omitempty"`
+}
+
+type SwwwOptions struct {
+	TransitionType     string  `json:"transition_type,omitempty"`
+	TransitionDuration float64 `json:"transition_duration,omitempty"`
+	TransitionFPS      int     `json:"transition_fps,omitempty"`
+}
+
+// WallpaperBackends lists every backend in auto-detection order.
+func WallpaperBackends(opts WallpaperOptions) []WallpaperBackend {
+	return []WallpaperBackend{
+		swwwBackend{opts.Swww},
+		hyprpaperBackend{},
+		swaybgBackend{mode: opts.Mode},
+		wbgBackend{},
+		commandBackend{template: opts.Command},
+	}
+}
+
+// ResolveWallpaperBackend picks the configured backend, or in auto mode the
+// first one whose daemon is running. With nothing running it falls back to
+// swww, which is what older versions always used.
+func ResolveWallpaperBackend(opts WallpaperOptions) (WallpaperBackend, error) {
+	all := WallpaperBackends(opts)
+	name := strings.ToLower(strings.TrimSpace(opts.Backend))
+	if name == "" || name == "auto" {
+		for _, b := range all {
+			if b.Running() {
+				return b, nil
+			}
+		}
+		return all[0], nil
+	}
+	for _, b := range all {
+		if b.Name() == name {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown wallpaper backend %q", opts.Backend)
+}
+
+// WallpaperFormats is every extension worth scanning for with this config.
+func WallpaperFormats(opts WallpaperOptions) []string {
+	exts := append([]string{}, theme.DefaultWallpaperExts...)
+	if b, err := ResolveWallpaperBackend(opts); err == nil {
+		exts = append(exts, b.Formats()...)
+	}
+	return exts
+}
+
+func setWallpapers(walls OutputWallpapers, opts WallpaperOptions) error {
+	b, err := ResolveWallpaperBackend(opts)
+	if err != nil {
+		return err
+	}
+	if err := b.Set(walls); err != nil {
+		return fmt.Errorf("wallpaper (%s): %w", b.Name(), err)
+	}
+	return nil
+}
+
+type swwwBackend struct{ opts SwwwOptions }
+
+func (swwwBackend) Name() string  { return "swww" }
+func (swwwBackend) Running() bool { return processRunning("swww-daemon") }
+func (swwwBackend) Formats() []string {
+	return []string{".gif", ".bmp", ".tga", ".tiff", ".pnm", ".avif"}
+}
+
+func (b swwwBackend) Set(walls OutputWallpapers) error {
+	if p, ok := walls[""]; ok {
+		if err := b.img("", p); err != nil {
+			return err
+		}
+	}
+	for _, o := range walls.outputs() {
+		if err := b.img(o, walls[o]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b swwwBackend) img(output, path string) error {
+	args := []string{"img"}
+	if output != "" {
+		args = append(args, "--outputs", output)
+	}
+	if b.opts.TransitionType != "" {
+		args = append(args, "--transition-type", b.opts.TransitionType)
+	}
+	if b.opts.TransitionDuration > 0 {
+		args = append(args, "--transition-duration", strconv.FormatFloat(b.opts.TransitionDuration, 'f', -1, 64))
+	}
+	if b.opts.TransitionFPS > 0 {
+		args = append(args, "--transition-fps", strconv.Itoa(b.opts.TransitionFPS))
+	}
+	return run("swww", append(args, path)...)
+}
+
+type swaybgBackend struct{ mode string }
+
+func (swaybgBackend) Name() string  { return "swaybg" }
+func (swaybgBackend) Running() bool { return processRunning("swaybg") }
+
+// swaybg loads through gdk-pixbuf, so avif/jxl work when the loaders are installed.
+func (swaybgBackend) Formats() []string {
+	return []string{".gif", ".bmp", ".tiff", ".avif", ".jxl"}
+}
+
+// swaybg takes every output in one process: -o NAME -i IMG -m MODE per output.
+func (b swaybgBackend) Set(walls OutputWallpapers) error {
+	mode := b.mode
+	if mode == "" {
+		mode = "fill"
+	}
+	var args []string
+	if p, ok := walls[""]; ok {
+		args = append(args, "-o", "*", "-i", p, "-m", mode)
+	}
+	for _, o := range walls.outputs() {
+		args = append(args, "-o", o, "-i", walls[o], "-m", mode)
+	}
+	return respawn("swaybg", "swaybg", args...)
+}
+
+type wbgBackend struct{}
+
+func (wbgBackend) Name() string      { return "wbg" }
+func (wbgBackend) Running() bool     { return processRunning("wbg") }
+func (wbgBackend) Formats() []string { return []string{".jxl"} }
+
+// wbg has no notion of outputs, so it only works when every output agrees.
+func (wbgBackend) Set(walls OutputWallpapers) error {
+	path := ""
+	for _, p := range walls {
+		if path != "" && p != path {
+			return errors.New("wbg can't show different wallpapers per output")
+		}
+		path = p
+	}
+	return respawn("wbg", "wbg", path)
+}
+
+// hyprpaperBackend talks to hyprpaper's IPC socket directly, so it works
+// without Hyprland's hyprctl.
+type hyprpaperBackend struct{}
+
+func (hyprpaperBackend) Name() string      { return "hyprpaper" }
+func (hyprpaperBackend) Running() bool     { return processRunning("hyprpaper") }
+func (hyprpaperBackend) Formats() []string { return []string{".jxl"} }
+
+func (hyprpaperBackend) Set(walls OutputWallpapers) error {
+	var reqs []string
+	if p, ok := walls[""]; ok {
+		reqs = append(reqs, "preload "+p, "wallpaper ,"+p)
+	}
+	for _, o := range walls.outputs() {
+		reqs = append(reqs, "preload "+walls[o], "wallpaper "+o+","+walls[o])
+	}
+	for _, req := range append(reqs, "unload unused") {
+		if err := hyprpaperRequest(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hyprpaperSocket() (string, error) {
+	rt := os.Getenv("XDG_RUNTIME_DIR")
+	if sig := os.Getenv("HYPRLAND_INSTANCE_SIGNATURE"); sig != "" {
+		return filepath.Join(rt, "hypr", sig, ".hyprpaper.sock"), nil
+	}
+	matches, _ := filepath.Glob(filepath.Join(rt, "hypr", "*", ".hyprpaper.sock"))
+	if len(matches) == 0 {
+		return "", errors.New("hyprpaper socket not found")
+	}
+	return matches[0], nil
+}
+
+func hyprpaperRequest(req string) error {
+	sock, err := hyprpaperSocket()
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("unix", sock, time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Write([]byte(req)); err != nil {
+		return err
+	}
+	reply, _ := bufio.NewReader(conn).ReadString('\n')
+	reply = strings.TrimSpace(reply)
+	if reply != "" && reply != "ok" {
+		return fmt.Errorf("hyprpaper %q: %s", req, reply)
+	}
+	return nil
+}
+
+// commandBackend runs a user template such as "feh --bg-fill {path}",
+// once for the default and once per output.
+type commandBackend struct{ template string }
+
+func (commandBackend) Name() string  { return "command" }
+func (commandBackend) Running() bool { return false }
+
+// The user's tool is unknown, so offer everything.
+func (commandBackend) Formats() []string {
+	return []string{".gif", ".bmp", ".tiff", ".avif", ".jxl"}
+}
+
+func (b commandBackend) Set(walls OutputWallpapers) error {
+	if strings.TrimSpace(b.template) == "" {
+		return errors.New("no wallpaper command configured")
+	}
+	if p, ok := walls[""]; ok {
+		if err := b.run("", p); err != nil {
+			return err
+		}
+	}
+	for _, o := range walls.outputs() {
+		if err := b.run(o, walls[o]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b commandBackend) run(output, path string) error {
+	fields := strings.Fields(b.template)
+	for i, f := range fields {
+		f = strings.ReplaceAll(f, "{path}", path)
+		fields[i] = strings.ReplaceAll(f, "{output}", output)
+	}
+	return run(fields[0], fields[1:]...)
+}
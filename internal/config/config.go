@@ -0,0 +1,133 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/app"
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// Config is the user's ~/.config/labwcchanger-tui/config.json.
+// Every field is optional; a missing file is the same as an empty one.
+type Config struct {
+	Profiles map[string]Profile         `json:"profiles,omitempty"`
+	Schedule Schedule                   `json:"schedule,omitempty"`
+	Walls    theme.WallpaperScanOptions `json:"walls,omitempty"`
+	Rotation Rotation                   `json:"rotation,omitempty"`
+	// StyleLabwc adds rc.xml <theme> options whenever a style is picked.
+	StyleLabwc map[string]app.LabwcOptions `json:"style_labwc,omitempty"`
+	// LivePreview starts the TUI with live preview on (p toggles it).
+	LivePreview bool `json:"live_preview,omitempty"`
+	app.Options
+}
+
+// ScanOptions combines the user's wallpaper filters with the formats the
+// wallpaper backend can actually display.
+func (c Config) ScanOptions() theme.ScanOptions {
+	walls := c.Walls
+	if len(walls.Extensions) == 0 {
+		walls.Extensions = app.WallpaperFormats(c.Wallpaper)
+	}
+	return theme.ScanOptions{Walls: walls}
+}
+
+// Profile is a named setup. Style (if set) is expanded with the same
+// matching the Style panel uses, then any explicit selections win.
+type Profile struct {
+	Style string `json:"style,omitempty"`
+	app.Selections
+}
+
+// Schedule coordinates are pointers so 0,0 is a place and not "unset".
+type Schedule struct {
+	Latitude  *float64        `json:"latitude,omitempty"`
+	Longitude *float64        `json:"longitude,omitempty"`
+	Entries   []ScheduleEntry `json:"entries,omitempty"`
+}
+
+// ScheduleEntry switches to Profile at At, which is "HH:MM", "sunrise" or
+// "sunset", optionally with an offset like "sunset-30m".
+type ScheduleEntry struct {
+	At      string `json:"at"`
+	Profile string `json:"profile"`
+}
+
+// Rotation cycles the wallpaper from the daemon.
+type Rotation struct {
+	// Source is "all" (default), "folder:<dir under walls>", "tag:<tag>"
+	// or "style" (same as "all"). Any source is narrowed to the wallpapers
+	// the active style matches once a style is active.
+	Source string `json:"source,omitempty"`
+	// Interval is a Go duration like "30m". Empty means only the
+	// next/previous commands rotate.
+	Interval string `json:"interval,omitempty"`
+	Shuffle  bool   `json:"shuffle,omitempty"`
+	// Paused starts the daemon with the timer paused.
+	Paused bool `json:"paused,omitempty"`
+}
+
+func Load() (Config, error) {
+	return LoadFile(theme.ConfigPath())
+}
+
+func LoadFile(path string) (Config, error) {
+	var cfg Config
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("read config: %w", err)
+	}
+	if err := json.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if err := cfg.validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+func (c Config) validate() error {
+	for name, p := range c.Profiles {
+		if p.Labwc == nil {
+			continue
+		}
+		if err := p.Labwc.Validate(); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+	}
+	for style, o := range c.StyleLabwc {
+		if err := o.Validate(); err != nil {
+			return fmt.Errorf("style_labwc %s: %w", style, err)
+		}
+	}
+	if err := c.Fuzzel.Validate(); err != nil {
+		return err
+	}
+	return c.Contrast.Validate()
+}
+
+// Resolve turns a profile into concrete selections against the scanned
+// catalog; palette is Options.PaletteOpenbox.
+func (p Profile) Resolve(cat theme.Catalog, palette bool) app.Selections {
+	var sel app.Selections
+	if p.Style != "" {
+		sel.OpenboxTheme, sel.GtkTheme, sel.IconTheme, sel.KittyTheme, sel.Wallpaper =
+			theme.ApplyStyle(p.Style, palette, cat.Openbox, cat.Gtk, cat.Icons, cat.Kitty, cat.Walls)
+	}
+	return sel.Overlay(p.Selections)
+}
+
+// Resolve expands a profile and puts the LabWC options configured for its
+// style underneath the profile's own.
+func (c Config) Resolve(p Profile, cat theme.Catalog) app.Selections {
+	sel := p.Resolve(cat, c.PaletteOpenbox)
+	if o, ok := c.StyleLabwc[p.Style]; ok && p.Style != "" {
+		sel = app.Selections{Labwc: &o}.Overlay(sel)
+	}
+	return sel
+}
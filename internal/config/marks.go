@@ -0,0 +1,169 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// Marks are the user's favorites, hidden items and tags, kept per category
+// (theme.Categories) in ~/.config/labwcchanger-tui/marks.json.
+type Marks map[string]*CategoryMarks
+
+type CategoryMarks struct {
+	Favorites []string            `json:"favorites,omitempty"`
+	Hidden    []string            `json:"hidden,omitempty"`
+	Tags      map[string][]string `json:"tags,omitempty"`
+}
+
+func LoadMarks() (Marks, error) {
+	marks := Marks{}
+	b, err := os.ReadFile(theme.MarksPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return marks, nil
+	}
+	if err != nil {
+		return marks, fmt.Errorf("read marks: %w", err)
+	}
+	if err := json.Unmarshal(b, &marks); err != nil {
+		return Marks{}, fmt.Errorf("parse marks.json: %w", err)
+	}
+	return marks, nil
+}
+
+func SaveMarks(marks Marks) error {
+	path := theme.MarksPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("mkdir config dir: %w", err)
+	}
+	b, err := json.MarshalIndent(marks, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write marks: %w", err)
+	}
+	return nil
+}
+
+func (m Marks) cat(category string) *CategoryMarks {
+	c := m[category]
+	if c == nil {
+		c = &CategoryMarks{}
+		m[category] = c
+	}
+	return c
+}
+
+func (m Marks) IsFavorite(category, item string) bool {
+	c := m[category]
+	return c != nil && contains(c.Favorites, item)
+}
+
+func (m Marks) IsHidden(category, item string) bool {
+	c := m[category]
+	return c != nil && contains(c.Hidden, item)
+}
+
+func (m Marks) Tags(category, item string) []string {
+	if c := m[category]; c != nil {
+		return c.Tags[item]
+	}
+	return nil
+}
+
+func (m Marks) HasTag(category, item, tag string) bool {
+	for _, t := range m.Tags(category, item) {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// ToggleFavorite flips the star and returns the new state.
+func (m Marks) ToggleFavorite(category, item string) bool {
+	c := m.cat(category)
+	c.Favorites, _ = toggle(c.Favorites, item)
+	return contains(c.Favorites, item)
+}
+
+func (m Marks) ToggleHidden(category, item string) bool {
+	c := m.cat(category)
+	c.Hidden, _ = toggle(c.Hidden, item)
+	return contains(c.Hidden, item)
+}
+
+// SetTags replaces an item's tags; tags are lowercased, deduplicated and sorted.
+func (m Marks) SetTags(category, item string, tags []string) {
+	c := m.cat(category)
+	set := map[string]bool{}
+	var clean []string
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !set[t] {
+			set[t] = true
+			clean = append(clean, t)
+		}
+	}
+	sort.Strings(clean)
+	if len(clean) == 0 {
+		delete(c.Tags, item)
+		return
+	}
+	if c.Tags == nil {
+		c.Tags = map[string][]string{}
+	}
+	c.Tags[item] = clean
+}
+
+// Tagged returns the items of a category carrying tag.
+func (m Marks) Tagged(category, tag string, items []string) []string {
+	var out []string
+	for _, it := range items {
+		if m.HasTag(category, it, tag) {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// Arrange drops hidden items (unless showHidden) and moves favorites to the
+// top, keeping the original order within each half.
+func (m Marks) Arrange(category string, items []string, showHidden bool) []string {
+	var favs, rest []string
+	for _, it := range items {
+		switch {
+		case !showHidden && m.IsHidden(category, it):
+		case m.IsFavorite(category, it):
+			favs = append(favs, it)
+		default:
+			rest = append(rest, it)
+		}
+	}
+	return append(favs, rest...)
+}
+
+func toggle(list []string, item string) ([]string, bool) {
+	for i, it := range list {
+		if it == item {
+			return append(list[:i:i], list[i+1:]...), false
+		}
+	}
+	return append(list, item), true
+}
+
+func contains(list []string, item string) bool {
+	for _, it := range list {
+		if it == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// Applies can take a while (kitten, waybar restart); everything else is instant.
+const callTimeout = 30 * time.Second
+
+type Client struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// ErrNotRunning tells callers to fall back to doing the work themselves.
+var ErrNotRunning = errors.New("daemon not running")
+
+func Dial() (*Client, error) {
+	conn, err := net.DialTimeout("unix", theme.SocketPath(), 500*time.Millisecond)
+	if err != nil {
+		return nil, ErrNotRunning
+	}
+	return &Client{conn: conn, r: bufio.NewReader(conn)}, nil
+}
+
+func (c *Client) Close() error { return c.conn.Close() }
+
+// Call sends req and waits for the reply. A reply with OK == false is
+// returned as an error.
+func (c *Client) Call(req Request) (Response, error) {
+	var resp Response
+	_ = c.conn.SetDeadline(time.Now().Add(callTimeout))
+	b, err := json.Marshal(req)
+	if err != nil {
+		return resp, err
+	}
+	if _, err := c.conn.Write(append(b, '\n')); err != nil {
+		return resp, fmt.Errorf("daemon: %w", err)
+	}
+	line, err := c.r.ReadBytes('\n')
+	if err != nil {
+		return resp, fmt.Errorf("daemon: %w", err)
+	}
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return resp, fmt.Errorf("daemon: bad reply: %w", err)
+	}
+	if !resp.OK {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+// Call is a one-shot Dial + Call + Close.
+func Call(req Request) (Response, error) {
+	c, err := Dial()
+	if err != nil {
+		return Response{}, err
+	}
+	defer c.Close()
+	return c.Call(req)
+}
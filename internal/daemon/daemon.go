@@ -0,0 +1,44 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/config"
+	"github.com/jaycee1285/labwcchanger-tui/internal/schedule"
+)
+
+// Run is the `daemon` subcommand: it serves the IPC socket and applies
+// scheduled profiles until ctx is cancelled.
+func Run(ctx context.Context, cfg config.Config) error {
+	sch, err := schedule.New(cfg.Schedule)
+	if err != nil {
+		return err
+	}
+	for _, e := range cfg.Schedule.Entries {
+		if _, ok := cfg.Profiles[e.Profile]; !ok {
+			return fmt.Errorf("schedule: unknown profile %q", e.Profile)
+		}
+	}
+
+	l, err := Listen()
+	if err != nil {
+		return err
+	}
+	// Closing unlinks the socket; do it before returning, not racing exit.
+	defer l.Close()
+	srv, err := NewServer(cfg)
+	if err != nil {
+		return err
+	}
+	go srv.rot.run(ctx)
+	go func() {
+		if err := srv.Serve(l); err != nil {
+			log.Printf("daemon: serve: %v", err)
+		}
+	}()
+
+	log.Printf("daemon: listening on %s, %d schedule entries", l.Addr(), len(cfg.Schedule.Entries))
+	return schedule.Run(ctx, sch, srv.ApplyProfile)
+}
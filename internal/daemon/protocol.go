@@ -0,0 +1,59 @@
+package daemon
+
+import (
+	"github.com/jaycee1285/labwcchanger-tui/internal/app"
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// The protocol is newline-delimited JSON: one Request per line, answered
+// by one Response per line, any number per connection.
+//
+//	{"cmd":"list","category":"gtk"}
+//	{"cmd":"current"}
+//	{"cmd":"select","category":"kitty","name":"Nord"}
+//	{"cmd":"apply"}                       applies the pending selection
+//	{"cmd":"apply","selections":{...}}    applies exactly these, as a whole
+//	{"cmd":"apply","dry_run":true}        only reports what apply would do
+//	{"cmd":"profile","name":"night"}
+//	{"cmd":"rollback"}
+//	{"cmd":"rescan"}
+//	{"cmd":"next"} / {"cmd":"previous"}   rotate the wallpaper
+//	{"cmd":"pause"} / {"cmd":"resume"}    stop/restart the rotation timer
+//
+// apply may carry "style" so the daemon knows which style is active
+// (rotation follows it); selecting a style does too.
+const (
+	CmdList     = "list"
+	CmdCurrent  = "current"
+	CmdSelect   = "select"
+	CmdApply    = "apply"
+	CmdProfile  = "profile"
+	CmdRollback = "rollback"
+	CmdRescan   = "rescan"
+	CmdNext     = "next"
+	CmdPrevious = "previous"
+	CmdPause    = "pause"
+	CmdResume   = "resume"
+)
+
+type Request struct {
+	Cmd        string          `json:"cmd"`
+	Category   string          `json:"category,omitempty"`
+	Name       string          `json:"name,omitempty"`
+	Style      string          `json:"style,omitempty"`
+	Selections *app.Selections `json:"selections,omitempty"`
+	DryRun     bool            `json:"dry_run,omitempty"`
+}
+
+type Response struct {
+	OK      bool            `json:"ok"`
+	Error   string          `json:"error,omitempty"`
+	Items   []string        `json:"items,omitempty"`
+	Catalog *theme.Catalog  `json:"catalog,omitempty"`
+	Current *app.Selections `json:"current,omitempty"`
+	Pending *app.Selections `json:"pending,omitempty"`
+	Style   string          `json:"style,omitempty"`
+
+	Rotation *RotationStatus `json:"rotation,omitempty"`
+	Report   *app.Report     `json:"report,omitempty"`
+}
@@ -0,0 +1,248 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/app"
+	"github.com/jaycee1285/labwcchanger-tui/internal/config"
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// How far back `previous` can go.
+const maxShown = 50
+
+type RotationStatus struct {
+	Source    string `json:"source"`
+	Interval  string `json:"interval,omitempty"`
+	Paused    bool   `json:"paused"`
+	Wallpaper string `json:"wallpaper,omitempty"`
+}
+
+// rotator cycles through a pool of wallpapers. Each cycle visits every
+// wallpaper once, in name order or shuffled, before starting over.
+type rotator struct {
+	srv      *Server
+	cfg      config.Rotation
+	interval time.Duration
+
+	mu     sync.Mutex
+	paused bool
+	cycle  []string
+	pos    int
+	shown  []string
+	kick   chan struct{}
+}
+
+func newRotator(srv *Server, cfg config.Rotation) (*rotator, error) {
+	r := &rotator{srv: srv, cfg: cfg, paused: cfg.Paused, pos: -1, kick: make(chan struct{}, 1)}
+	if cfg.Interval != "" {
+		d, err := time.ParseDuration(cfg.Interval)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("rotation: bad interval %q", cfg.Interval)
+		}
+		r.interval = d
+	}
+	if _, err := r.pool(theme.Catalog{}, ""); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// pool is the set of wallpapers the source allows right now, narrowed to
+// the ones the active style matches once there is one, minus the ones
+// hidden in the TUI. Marks are read each time so edits made in the TUI
+// apply without a restart.
+func (r *rotator) pool(cat theme.Catalog, style string) ([]string, error) {
+	marks, err := config.LoadMarks()
+	if err != nil {
+		return nil, err
+	}
+	var walls []string
+	src := r.cfg.Source
+	switch {
+	case src == "" || src == "all" || src == "style":
+		walls = cat.Walls
+	case strings.HasPrefix(src, "tag:"):
+		walls = marks.Tagged("walls", strings.TrimPrefix(src, "tag:"), cat.Walls)
+	case strings.HasPrefix(src, "folder:"):
+		dir := strings.Trim(strings.TrimPrefix(src, "folder:"), "/") + "/"
+		for _, w := range cat.Walls {
+			if strings.HasPrefix(w, dir) {
+				walls = append(walls, w)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("rotation: unknown source %q", src)
+	}
+	if style != "" {
+		walls = theme.StyleMatches(style, walls)
+	}
+	var out []string
+	for _, w := range walls {
+		if !marks.IsHidden("walls", w) {
+			out = append(out, w)
+		}
+	}
+	return out, nil
+}
+
+func (r *rotator) run(ctx context.Context) {
+	if r.interval == 0 {
+		return
+	}
+	t := time.NewTimer(r.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.kick:
+			// A manual step restarts the countdown.
+			if !t.Stop() {
+				select {
+				case <-t.C:
+				default:
+				}
+			}
+		case <-t.C:
+			if !r.isPaused() {
+				if err := r.step(1); err != nil {
+					log.Printf("rotation: %v", err)
+				}
+			}
+		}
+		t.Reset(r.interval)
+	}
+}
+
+func (r *rotator) isPaused() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.paused
+}
+
+func (r *rotator) setPaused(p bool) {
+	r.mu.Lock()
+	r.paused = p
+	r.mu.Unlock()
+}
+
+// step shows the next (dir > 0) or previously shown (dir < 0) wallpaper.
+func (r *rotator) step(dir int) error {
+	st := r.srv.snapshot()
+	pool, err := r.pool(st.catalog, st.style)
+	if err != nil {
+		return err
+	}
+	if len(pool) == 0 {
+		return errors.New("no wallpapers to rotate through")
+	}
+
+	r.mu.Lock()
+	var wall string
+	if dir < 0 {
+		if len(r.shown) < 2 {
+			r.mu.Unlock()
+			return errors.New("no previous wallpaper")
+		}
+		r.shown = r.shown[:len(r.shown)-1]
+		wall = r.shown[len(r.shown)-1]
+		if r.pos > 0 {
+			r.pos--
+		}
+	} else {
+		if !samePool(r.cycle, pool) || r.pos+1 >= len(r.cycle) {
+			r.cycle = r.newCycle(pool, st.current.Wallpaper)
+			r.pos = -1
+		}
+		r.pos++
+		wall = r.cycle[r.pos]
+		r.shown = append(r.shown, wall)
+		if len(r.shown) > maxShown {
+			r.shown = r.shown[1:]
+		}
+	}
+	r.mu.Unlock()
+
+	select {
+	case r.kick <- struct{}{}:
+	default:
+	}
+	return r.srv.setWallpaper(wall)
+}
+
+// newCycle orders the pool for one pass. When shuffling it avoids opening
+// with the wallpaper already on screen.
+func (r *rotator) newCycle(pool []string, onScreen string) []string {
+	cycle := append([]string{}, pool...)
+	if !r.cfg.Shuffle {
+		// Continue after the current wallpaper rather than from the top.
+		for i, w := range cycle {
+			if w == onScreen {
+				cycle = append(cycle[i+1:], cycle[:i+1]...)
+				break
+			}
+		}
+		return cycle
+	}
+	rand.Shuffle(len(cycle), func(i, j int) { cycle[i], cycle[j] = cycle[j], cycle[i] })
+	if len(cycle) > 1 && cycle[0] == onScreen {
+		cycle[0], cycle[len(cycle)-1] = cycle[len(cycle)-1], cycle[0]
+	}
+	return cycle
+}
+
+func (r *rotator) status() RotationStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := RotationStatus{Source: r.cfg.Source, Interval: r.cfg.Interval, Paused: r.paused}
+	if st.Source == "" {
+		st.Source = "all"
+	}
+	if len(r.shown) > 0 {
+		st.Wallpaper = r.shown[len(r.shown)-1]
+	}
+	return st
+}
+
+func samePool(cycle, pool []string) bool {
+	if len(cycle) != len(pool) {
+		return false
+	}
+	in := make(map[string]bool, len(pool))
+	for _, p := range pool {
+		in[p] = true
+	}
+	for _, c := range cycle {
+		if !in[c] {
+			return false
+		}
+	}
+	return true
+}
+
+// setWallpaper changes only the wallpaper and doesn't touch rollback:
+// rotation isn't something you'd want to undo step by step.
+func (s *Server) setWallpaper(wall string) error {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+	s.mu.Lock()
+	sel := s.current
+	s.mu.Unlock()
+	sel.Wallpaper = wall
+	if err := app.SetWallpaper(sel, s.cfg.Options); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.current.Wallpaper = wall
+	s.pending.Wallpaper = wall
+	s.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,296 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/app"
+	"github.com/jaycee1285/labwcchanger-tui/internal/config"
+	"github.com/jaycee1285/labwcchanger-tui/internal/history"
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// How many earlier setups `rollback` can walk back through.
+const maxRollback = 20
+
+type Server struct {
+	cfg config.Config
+
+	mu           sync.Mutex
+	catalog      theme.Catalog
+	current      app.Selections
+	pending      app.Selections
+	previous     []app.Selections
+	style        string // last applied style, "" if none
+	pendingStyle string
+
+	rot *rotator
+
+	// Serialises applies so a scheduled switch can't interleave with one
+	// from the TUI; held without mu so list/current stay instant.
+	applyMu sync.Mutex
+}
+
+func NewServer(cfg config.Config) (*Server, error) {
+	s := &Server{cfg: cfg}
+	rot, err := newRotator(s, cfg.Rotation)
+	if err != nil {
+		return nil, err
+	}
+	s.rot = rot
+	s.rescan()
+	cs := theme.LoadCurrentSettings()
+	s.current = app.Selections{
+		OpenboxTheme: cs.OpenboxTheme,
+		GtkTheme:     cs.GtkTheme,
+		IconTheme:    cs.IconTheme,
+	}
+	s.pending = s.current
+	return s, nil
+}
+
+func (s *Server) rescan() {
+	cat := theme.ScanCatalog(s.cfg.ScanOptions())
+	s.mu.Lock()
+	s.catalog = cat
+	s.mu.Unlock()
+}
+
+// Listen binds the socket, replacing a stale one left by a crashed daemon
+// but refusing to steal it from a live one.
+func Listen() (net.Listener, error) {
+	path := theme.SocketPath()
+	if c, err := net.DialTimeout("unix", path, 200*time.Millisecond); err == nil {
+		c.Close()
+		return nil, fmt.Errorf("daemon already running on %s", path)
+	}
+	_ = os.Remove(path)
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen %s: %w", path, err)
+	}
+	_ = os.Chmod(path, 0o600)
+	return l, nil
+}
+
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	sc := bufio.NewScanner(conn)
+	enc := json.NewEncoder(conn)
+	for sc.Scan() {
+		var req Request
+		var resp Response
+		if err := json.Unmarshal(sc.Bytes(), &req); err != nil {
+			resp = Response{Error: "bad request: " + err.Error()}
+		} else {
+			resp = s.dispatch(req)
+		}
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) dispatch(req Request) Response {
+	var err error
+	var rep app.Report
+	switch req.Cmd {
+	case CmdList:
+		return s.list(req.Category)
+	case CmdCurrent:
+	case CmdSelect:
+		err = s.selectItem(req.Category, req.Name)
+	case CmdApply:
+		st := s.snapshot()
+		sel, style := st.pending, st.pendingStyle
+		if req.Selections != nil {
+			// Sent selections are complete; overlaying them would keep
+			// anything the client cleared.
+			sel = *req.Selections
+		}
+		if req.Style != "" {
+			style = req.Style
+		}
+		if req.DryRun {
+			opts := s.cfg.Options
+			opts.DryRun = true
+			rep, err = app.Apply(sel, opts)
+			break
+		}
+		rep, err = s.apply(sel, style, false)
+	case CmdProfile:
+		err = s.ApplyProfile(req.Name)
+	case CmdRollback:
+		rep, err = s.rollback()
+	case CmdRescan:
+		s.rescan()
+	case CmdNext:
+		err = s.rot.step(1)
+	case CmdPrevious:
+		err = s.rot.step(-1)
+	case CmdPause, CmdResume:
+		s.rot.setPaused(req.Cmd == CmdPause)
+	default:
+		err = fmt.Errorf("unknown command %q", req.Cmd)
+	}
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+	st := s.snapshot()
+	rot := s.rot.status()
+	resp := Response{OK: true, Current: &st.current, Pending: &st.pending, Style: st.style, Rotation: &rot}
+	if req.Cmd == CmdApply || req.Cmd == CmdRollback {
+		resp.Report = &rep
+	}
+	return resp
+}
+
+type state struct {
+	catalog      theme.Catalog
+	current      app.Selections
+	pending      app.Selections
+	style        string
+	pendingStyle string
+}
+
+func (s *Server) snapshot() state {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return state{
+		catalog:      s.catalog,
+		current:      s.current,
+		pending:      s.pending,
+		style:        s.style,
+		pendingStyle: s.pendingStyle,
+	}
+}
+
+func (s *Server) list(category string) Response {
+	cat := s.snapshot().catalog
+	if category == "" {
+		return Response{OK: true, Catalog: &cat}
+	}
+	items, ok := cat.Category(category)
+	if !ok {
+		return Response{Error: fmt.Sprintf("unknown category %q", category)}
+	}
+	return Response{OK: true, Items: items}
+}
+
+func (s *Server) selectItem(category, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// "walls:DP-1" is checked against the walls list.
+	base, _, _ := strings.Cut(category, ":")
+	items, ok := s.catalog.Category(base)
+	if !ok {
+		return fmt.Errorf("unknown category %q", category)
+	}
+	if !contains(items, name) {
+		return fmt.Errorf("%s: no such item %q", category, name)
+	}
+	if category == "style" || category == "styles" {
+		s.pending = s.pending.Overlay(s.cfg.Resolve(config.Profile{Style: name}, s.catalog))
+		s.pendingStyle = name
+		return nil
+	}
+	s.pending.Set(category, name)
+	return nil
+}
+
+func (s *Server) ApplyProfile(name string) error {
+	p, ok := s.cfg.Profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+	// Rescan so themes installed since the daemon started are matched.
+	s.rescan()
+	rep, err := s.apply(s.cfg.Resolve(p, s.snapshot().catalog), p.Style, true)
+	for _, w := range rep.Warnings {
+		log.Printf("profile %s: %s", name, w)
+	}
+	return err
+}
+
+// apply runs app.Apply and records the result for rollback and in the
+// history file. A non-empty style becomes the active style. Client
+// applies send a whole selection that replaces the current one; profiles
+// may name only some categories, so overlay lays them over it instead.
+func (s *Server) apply(sel app.Selections, style string, overlay bool) (app.Report, error) {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+	rep, err := history.Apply(sel, style, s.cfg.Options)
+	if err != nil {
+		return rep, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.previous = append(s.previous, s.current)
+	if len(s.previous) > maxRollback {
+		s.previous = s.previous[1:]
+	}
+	if overlay {
+		sel = s.current.Overlay(sel)
+	}
+	s.current = sel
+	s.pending = sel
+	if style != "" {
+		s.style = style
+	}
+	s.pendingStyle = ""
+	return rep, nil
+}
+
+func (s *Server) rollback() (app.Report, error) {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+	s.mu.Lock()
+	if len(s.previous) == 0 {
+		s.mu.Unlock()
+		return app.Report{}, errors.New("nothing to roll back to")
+	}
+	prev := s.previous[len(s.previous)-1]
+	s.mu.Unlock()
+
+	rep, err := history.Apply(prev, "", s.cfg.Options)
+	if err != nil {
+		return rep, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.previous = s.previous[:len(s.previous)-1]
+	s.current = prev
+	s.pending = prev
+	log.Printf("daemon: rolled back")
+	return rep, nil
+}
+
+func contains(items []string, name string) bool {
+	for _, it := range items {
+		if it == name {
+			return true
+		}
+	}
+	return false
+}
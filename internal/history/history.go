@@ -0,0 +1,176 @@
+// Package history records every successful apply in a JSON-lines file so
+// past setups can be browsed and re-applied.
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/app"
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// The file is trimmed back to keepEntries once it passes maxEntries, so
+// it doesn't have to be rewritten on every apply.
+const (
+	keepEntries = 200
+	maxEntries  = 300
+)
+
+type Entry struct {
+	Time       time.Time      `json:"time"`
+	Selections app.Selections `json:"selections"`
+	Style      string         `json:"style,omitempty"`
+	Report     app.Report     `json:"report"`
+}
+
+// Summary is the one-line description used by the CLI and the TUI.
+func (e Entry) Summary() string {
+	s := e.Selections
+	var parts []string
+	add := func(label, v string) {
+		if v != "" {
+			parts = append(parts, label+" "+v)
+		}
+	}
+	add("style", e.Style)
+	add("gtk", s.GtkTheme)
+	add("icons", s.IconTheme)
+	add("labwc", s.OpenboxTheme)
+	add("kitty", s.KittyTheme)
+	if s.Wallpaper != "" {
+		add("wall", filepath.Base(s.Wallpaper))
+	}
+	if len(s.Wallpapers) > 0 {
+		add("outputs", fmt.Sprint(len(s.Wallpapers)))
+	}
+	if s.Labwc != nil {
+		add("labwc options", s.Labwc.Summary())
+	}
+	if len(parts) == 0 {
+		return "(nothing selected)"
+	}
+	return strings.Join(parts, " · ")
+}
+
+// Load returns all entries, oldest first. Lines that don't parse (a
+// partial write, a newer format) are skipped.
+func Load() ([]Entry, error) {
+	data, err := os.ReadFile(theme.HistoryPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+	var out []Entry
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for sc.Scan() {
+		var e Entry
+		if json.Unmarshal(sc.Bytes(), &e) == nil {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// Nth returns the n-th most recent entry, counting from 1.
+func Nth(n int) (Entry, error) {
+	entries, err := Load()
+	if err != nil {
+		return Entry{}, err
+	}
+	if n < 1 || n > len(entries) {
+		return Entry{}, fmt.Errorf("history has %d entries, no #%d", len(entries), n)
+	}
+	return entries[len(entries)-n], nil
+}
+
+// Append adds e to the file, trimming it when it has grown too long. The
+// daemon and the TUI both append, so this holds an exclusive lock on a
+// file next to the history for the whole read-trim-write.
+func Append(e Entry) error {
+	path := theme.HistoryPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("history dir: %w", err)
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	unlock, err := lock(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("open history: %w", err)
+	}
+	_, err = f.Write(append(line, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("write history: %w", err)
+	}
+	return trim(path)
+}
+
+// lock takes the lock beside path; trim replaces the history file itself,
+// so locking that would leave a later writer holding a stale inode.
+func lock(path string) (func(), error) {
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("lock history: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("lock history: %w", err)
+	}
+	// Closing the file releases the lock.
+	return func() { f.Close() }, nil
+}
+
+func trim(path string) error {
+	entries, err := Load()
+	if err != nil || len(entries) <= maxEntries {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, e := range entries[len(entries)-keepEntries:] {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("trim history: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Apply runs app.Apply and records it when it succeeds (dry runs aren't
+// recorded). Failing to write the history is reported as a warning, not
+// an error: the theme is already applied.
+func Apply(sel app.Selections, style string, opts app.Options) (app.Report, error) {
+	rep, err := app.Apply(sel, opts)
+	if err != nil || opts.DryRun {
+		return rep, err
+	}
+	if herr := Append(Entry{Time: time.Now(), Selections: sel, Style: style, Report: rep}); herr != nil {
+		rep.Warnings = append(rep.Warnings, herr.Error())
+	}
+	return rep, nil
+}
@@ -0,0 +1,168 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/config"
+)
+
+// How often Run re-checks even when no transition is due. Catches
+// suspend/resume and clock changes without listening for either.
+const recheckInterval = time.Minute
+
+type Event struct {
+	At      time.Time
+	Profile string
+}
+
+type Scheduler struct {
+	lat, lon float64
+	specs    []spec
+}
+
+type spec struct {
+	sun     string // "", "sunrise" or "sunset"
+	hour    int    // time of day when sun == ""
+	minute  int
+	offset  time.Duration
+	profile string
+}
+
+func New(s config.Schedule) (*Scheduler, error) {
+	sch := &Scheduler{}
+	located := s.Latitude != nil && s.Longitude != nil
+	if located {
+		sch.lat, sch.lon = *s.Latitude, *s.Longitude
+	}
+	for i, e := range s.Entries {
+		if e.Profile == "" {
+			return nil, fmt.Errorf("schedule entry %d: missing profile", i+1)
+		}
+		sp, err := parseAt(e.At)
+		if err != nil {
+			return nil, fmt.Errorf("schedule entry %d: %w", i+1, err)
+		}
+		if sp.sun != "" && !located {
+			return nil, fmt.Errorf("schedule entry %d: %s needs latitude and longitude", i+1, sp.sun)
+		}
+		sp.profile = e.Profile
+		sch.specs = append(sch.specs, sp)
+	}
+	return sch, nil
+}
+
+func parseAt(at string) (spec, error) {
+	at = strings.ToLower(strings.TrimSpace(at))
+	for _, sun := range []string{"sunrise", "sunset"} {
+		if !strings.HasPrefix(at, sun) {
+			continue
+		}
+		sp := spec{sun: sun}
+		if rest := strings.TrimSpace(at[len(sun):]); rest != "" {
+			d, err := time.ParseDuration(strings.ReplaceAll(rest, " ", ""))
+			if err != nil {
+				return spec{}, fmt.Errorf("bad offset %q", rest)
+			}
+			sp.offset = d
+		}
+		return sp, nil
+	}
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return spec{}, fmt.Errorf("bad time %q (want HH:MM, sunrise or sunset)", at)
+	}
+	return spec{hour: t.Hour(), minute: t.Minute()}, nil
+}
+
+// occurrences lists every entry's transition on the calendar day of day.
+// Solar entries are skipped on days the sun doesn't rise or set.
+func (s *Scheduler) occurrences(day time.Time) []Event {
+	y, m, d := day.Date()
+	var rise, set time.Time
+	var sunOK, sunDone bool
+	var out []Event
+	for _, sp := range s.specs {
+		var at time.Time
+		switch sp.sun {
+		case "":
+			at = time.Date(y, m, d, sp.hour, sp.minute, 0, 0, day.Location())
+		default:
+			if !sunDone {
+				rise, set, sunOK = SunTimes(day, s.lat, s.lon)
+				sunDone = true
+			}
+			if !sunOK {
+				continue
+			}
+			at = rise
+			if sp.sun == "sunset" {
+				at = set
+			}
+			at = at.Add(sp.offset)
+		}
+		out = append(out, Event{At: at, Profile: sp.profile})
+	}
+	return out
+}
+
+// Active returns the profile whose transition most recently passed.
+func (s *Scheduler) Active(now time.Time) (string, bool) {
+	var best Event
+	for _, off := range []int{-2, -1, 0} {
+		for _, ev := range s.occurrences(now.AddDate(0, 0, off)) {
+			if !ev.At.After(now) && ev.At.After(best.At) {
+				best = ev
+			}
+		}
+	}
+	return best.Profile, best.Profile != ""
+}
+
+// Next returns the first transition strictly after now.
+func (s *Scheduler) Next(now time.Time) (Event, bool) {
+	var best Event
+	for _, off := range []int{0, 1, 2} {
+		for _, ev := range s.occurrences(now.AddDate(0, 0, off)) {
+			if ev.At.After(now) && (best.At.IsZero() || ev.At.Before(best.At)) {
+				best = ev
+			}
+		}
+	}
+	return best, !best.At.IsZero()
+}
+
+// Run applies the active profile right away, then again at every
+// transition, until ctx is cancelled.
+func Run(ctx context.Context, s *Scheduler, apply func(profile string) error) error {
+	if len(s.specs) == 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	last := ""
+	for {
+		now := time.Now()
+		if p, ok := s.Active(now); ok && p != last {
+			if err := apply(p); err != nil {
+				log.Printf("schedule: apply %q: %v", p, err)
+			} else {
+				log.Printf("schedule: applied %q", p)
+				last = p
+			}
+		}
+		wait := recheckInterval
+		if ev, ok := s.Next(now); ok {
+			if d := ev.At.Sub(now); d < wait {
+				wait = d
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/config"
+)
+
+var (
+	cet  = time.FixedZone("CET", 1*3600)
+	cest = time.FixedZone("CEST", 2*3600)
+	aest = time.FixedZone("AEST", 10*3600)
+)
+
+func clock(loc *time.Location, y int, m time.Month, d, hh, mm int) time.Time {
+	return time.Date(y, m, d, hh, mm, 0, 0, loc)
+}
+
+func float(f float64) *float64 { return &f }
+
+// Published sunrise/sunset times are rounded to the minute and use
+// slightly different refraction models, so allow a little slack.
+const sunSlack = 3 * time.Minute
+
+func near(a, b time.Time) bool {
+	d := a.Sub(b)
+	return d > -sunSlack && d < sunSlack
+}
+
+func TestSunTimes(t *testing.T) {
+	tests := []struct {
+		name      string
+		date      time.Time
+		lat, lon  float64
+		rise, set time.Time
+		wantOK    bool
+	}{
+		{"berlin summer solstice", clock(cest, 2024, 6, 21, 12, 0), 52.52, 13.405,
+			clock(cest, 2024, 6, 21, 4, 43), clock(cest, 2024, 6, 21, 21, 33), true},
+		{"berlin winter solstice", clock(cet, 2024, 12, 21, 12, 0), 52.52, 13.405,
+			clock(cet, 2024, 12, 21, 8, 15), clock(cet, 2024, 12, 21, 15, 54), true},
+		{"sydney winter solstice", clock(aest, 2024, 6, 21, 12, 0), -33.87, 151.21,
+			clock(aest, 2024, 6, 21, 7, 0), clock(aest, 2024, 6, 21, 16, 54), true},
+		{"null island equinox", clock(time.UTC, 2024, 3, 20, 12, 0), 0, 0,
+			clock(time.UTC, 2024, 3, 20, 6, 4), clock(time.UTC, 2024, 3, 20, 18, 11), true},
+		{"tromso polar day", clock(time.UTC, 2024, 6, 21, 12, 0), 69.65, 18.96,
+			time.Time{}, time.Time{}, false},
+		{"tromso polar night", clock(time.UTC, 2024, 12, 21, 12, 0), 69.65, 18.96,
+			time.Time{}, time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rise, set, ok := SunTimes(tt.date, tt.lat, tt.lon)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if !near(rise, tt.rise) {
+				t.Errorf("sunrise = %s, want %s", rise, tt.rise)
+			}
+			if !near(set, tt.set) {
+				t.Errorf("sunset = %s, want %s", set, tt.set)
+			}
+			if rise.Location() != tt.date.Location() {
+				t.Errorf("sunrise in %s, want %s", rise.Location(), tt.date.Location())
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	sun := []config.ScheduleEntry{{At: "sunset", Profile: "night"}}
+	tests := []struct {
+		name    string
+		s       config.Schedule
+		wantErr bool
+	}{
+		{"fixed times need no location", config.Schedule{Entries: []config.ScheduleEntry{{At: "07:30", Profile: "day"}}}, false},
+		{"sun without location", config.Schedule{Entries: sun}, true},
+		{"sun with only latitude", config.Schedule{Latitude: float(52.52), Entries: sun}, true},
+		{"sun at 0,0", config.Schedule{Latitude: float(0), Longitude: float(0), Entries: sun}, false},
+		{"missing profile", config.Schedule{Entries: []config.ScheduleEntry{{At: "07:30"}}}, true},
+		{"bad time", config.Schedule{Entries: []config.ScheduleEntry{{At: "25:00", Profile: "day"}}}, true},
+		{"bad offset", config.Schedule{Latitude: float(1), Longitude: float(1),
+			Entries: []config.ScheduleEntry{{At: "sunset-soon", Profile: "night"}}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func mustNew(t *testing.T, s config.Schedule) *Scheduler {
+	t.Helper()
+	sch, err := New(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sch
+}
+
+func berlin(entries ...config.ScheduleEntry) config.Schedule {
+	return config.Schedule{Latitude: float(52.52), Longitude: float(13.405), Entries: entries}
+}
+
+func polar(entries ...config.ScheduleEntry) config.Schedule {
+	return config.Schedule{Latitude: float(69.65), Longitude: float(18.96), Entries: entries}
+}
+
+func TestActive(t *testing.T) {
+	dayNight := []config.ScheduleEntry{
+		{At: "sunrise", Profile: "day"},
+		{At: "sunset-30m", Profile: "night"},
+	}
+	fixed := []config.ScheduleEntry{
+		{At: "08:00", Profile: "work"},
+		{At: "18:00", Profile: "home"},
+	}
+	tests := []struct {
+		name   string
+		s      config.Schedule
+		now    time.Time
+		want   string
+		wantOK bool
+	}{
+		{"before first fixed entry wraps to yesterday", config.Schedule{Entries: fixed}, clock(cet, 2024, 3, 5, 7, 59), "home", true},
+		{"exactly at a transition", config.Schedule{Entries: fixed}, clock(cet, 2024, 3, 5, 8, 0), "work", true},
+		{"between fixed entries", config.Schedule{Entries: fixed}, clock(cet, 2024, 3, 5, 12, 0), "work", true},
+		{"after last fixed entry", config.Schedule{Entries: fixed}, clock(cet, 2024, 3, 5, 23, 0), "home", true},
+		{"berlin before sunrise", berlin(dayNight...), clock(cest, 2024, 6, 21, 4, 30), "night", true},
+		{"berlin after sunrise", berlin(dayNight...), clock(cest, 2024, 6, 21, 5, 0), "day", true},
+		{"berlin before offset sunset", berlin(dayNight...), clock(cest, 2024, 6, 21, 20, 55), "day", true},
+		{"berlin after offset sunset", berlin(dayNight...), clock(cest, 2024, 6, 21, 21, 10), "night", true},
+		{"polar day keeps fixed entries", polar(append(dayNight, fixed...)...), clock(time.UTC, 2024, 6, 21, 12, 0), "work", true},
+		{"polar night without fixed entries", polar(dayNight...), clock(time.UTC, 2024, 12, 21, 12, 0), "", false},
+		{"no entries", config.Schedule{}, clock(cet, 2024, 3, 5, 12, 0), "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := mustNew(t, tt.s).Active(tt.now)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Active = %q, %v; want %q, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	dayNight := []config.ScheduleEntry{
+		{At: "sunrise+15m", Profile: "day"},
+		{At: "sunset", Profile: "night"},
+	}
+	fixed := []config.ScheduleEntry{
+		{At: "08:00", Profile: "work"},
+		{At: "18:00", Profile: "home"},
+	}
+	tests := []struct {
+		name    string
+		s       config.Schedule
+		now     time.Time
+		want    Event
+		wantOK  bool
+		sunTime bool // want.At is a solar time, compare with slack
+	}{
+		{"next fixed entry today", config.Schedule{Entries: fixed}, clock(cet, 2024, 3, 5, 12, 0),
+			Event{clock(cet, 2024, 3, 5, 18, 0), "home"}, true, false},
+		{"at a transition is not next", config.Schedule{Entries: fixed}, clock(cet, 2024, 3, 5, 18, 0),
+			Event{clock(cet, 2024, 3, 6, 8, 0), "work"}, true, false},
+		{"berlin sunrise with offset", berlin(dayNight...), clock(cest, 2024, 6, 21, 1, 0),
+			Event{clock(cest, 2024, 6, 21, 4, 58), "day"}, true, true},
+		{"berlin sunset", berlin(dayNight...), clock(cest, 2024, 6, 21, 12, 0),
+			Event{clock(cest, 2024, 6, 21, 21, 33), "night"}, true, true},
+		{"polar night falls back to fixed entries", polar(append(dayNight, fixed...)...), clock(time.UTC, 2024, 12, 21, 12, 0),
+			Event{clock(time.UTC, 2024, 12, 21, 18, 0), "home"}, true, false},
+		{"polar day has no transitions", polar(dayNight...), clock(time.UTC, 2024, 6, 21, 12, 0),
+			Event{}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := mustNew(t, tt.s).Next(tt.now)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v (got %v)", ok, tt.wantOK, got)
+			}
+			if got.Profile != tt.want.Profile {
+				t.Errorf("profile = %q, want %q", got.Profile, tt.want.Profile)
+			}
+			if tt.sunTime && !near(got.At, tt.want.At) || !tt.sunTime && !got.At.Equal(tt.want.At) {
+				t.Errorf("at = %s, want %s", got.At, tt.want.At)
+			}
+		})
+	}
+}
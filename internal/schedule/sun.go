@@ -0,0 +1,50 @@
+package schedule
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	julianUnixEpoch = 2440587.5
+	julian2000      = 2451545.0
+	deg             = math.Pi / 180
+)
+
+// SunTimes returns sunrise and sunset for the calendar day of date (in
+// date's location) at the given latitude/longitude, using the standard
+// sunrise equation. ok is false during polar day or night.
+func SunTimes(date time.Time, lat, lon float64) (sunrise, sunset time.Time, ok bool) {
+	y, m, d := date.Date()
+	noonUTC := time.Date(y, m, d, 12, 0, 0, 0, time.UTC)
+	n := math.Round(toJulian(noonUTC) - julian2000 + 0.0008)
+
+	// Mean solar time and anomaly.
+	jStar := n - lon/360
+	M := math.Mod(357.5291+0.98560028*jStar, 360)
+	C := 1.9148*math.Sin(M*deg) + 0.0200*math.Sin(2*M*deg) + 0.0003*math.Sin(3*M*deg)
+	lambda := math.Mod(M+C+180+102.9372, 360)
+	transit := julian2000 + jStar + 0.0053*math.Sin(M*deg) - 0.0069*math.Sin(2*lambda*deg)
+
+	sinDecl := math.Sin(lambda*deg) * math.Sin(23.4397*deg)
+	cosDecl := math.Cos(math.Asin(sinDecl))
+	cosHour := (math.Sin(-0.833*deg) - math.Sin(lat*deg)*sinDecl) / (math.Cos(lat*deg) * cosDecl)
+	if cosHour < -1 || cosHour > 1 {
+		return time.Time{}, time.Time{}, false
+	}
+	hour := math.Acos(cosHour) / deg
+
+	loc := date.Location()
+	sunrise = fromJulian(transit - hour/360).In(loc)
+	sunset = fromJulian(transit + hour/360).In(loc)
+	return sunrise, sunset, true
+}
+
+func toJulian(t time.Time) float64 {
+	return float64(t.Unix())/86400 + julianUnixEpoch
+}
+
+func fromJulian(j float64) time.Time {
+	secs := (j - julianUnixEpoch) * 86400
+	return time.Unix(int64(math.Round(secs)), 0)
+}
@@ -0,0 +1,95 @@
+package theme
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// The catalog is cached between runs. Each section remembers the mtime
+// and inode of every directory (and kitty theme file) it was built from,
+// and is only scanned again once one of them changes.
+
+// catalogCacheVersion is bumped whenever Catalog changes shape.
+const catalogCacheVersion = 1
+
+type catalogCache struct {
+	Version int               `json:"version"`
+	Options ScanOptions       `json:"options"`
+	Stamps  map[string]stamps `json:"stamps"`
+	Catalog Catalog           `json:"catalog"`
+}
+
+// stamps are path → state at scan time; a missing path has the zero stamp.
+type stamps map[string]stamp
+
+type stamp struct {
+	Mtime int64  `json:"m,omitempty"`
+	Ino   uint64 `json:"i,omitempty"`
+}
+
+func stampOf(path string) stamp {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return stamp{}
+	}
+	s := stamp{Mtime: fi.ModTime().UnixNano()}
+	if sys, ok := fi.Sys().(*syscall.Stat_t); ok {
+		s.Ino = sys.Ino
+	}
+	return s
+}
+
+func (s stamps) add(paths ...string) {
+	for _, p := range paths {
+		s[p] = stampOf(p)
+	}
+}
+
+func (s stamps) fresh() bool {
+	for p, st := range s {
+		if stampOf(p) != st {
+			return false
+		}
+	}
+	return true
+}
+
+// CachedCatalog returns the catalog of the last scan without checking
+// whether it is still current, for showing something right away.
+func CachedCatalog() (Catalog, bool) {
+	c, ok := loadCatalogCache()
+	if !ok {
+		return Catalog{}, false
+	}
+	return c.Catalog, true
+}
+
+func loadCatalogCache() (catalogCache, bool) {
+	var c catalogCache
+	b, err := os.ReadFile(CatalogCachePath())
+	if err != nil || json.Unmarshal(b, &c) != nil || c.Version != catalogCacheVersion {
+		return catalogCache{}, false
+	}
+	return c, true
+}
+
+// saveCatalogCache is best effort: without a cache the next start scans.
+func saveCatalogCache(c catalogCache) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return
+	}
+	path := CatalogCachePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+	}
+}
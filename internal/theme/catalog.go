@@ -0,0 +1,201 @@
+package theme
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+)
+
+// Catalog is everything the scanners found, in the order the TUI shows it.
+type Catalog struct {
+	Openbox []string `json:"openbox"`
+	Gtk     []string `json:"gtk"`
+	Icons   []string `json:"icons"`
+	Kitty   []string `json:"kitty"`
+	Walls   []string `json:"walls"`
+	Styles  []string `json:"styles"`
+
+	WallInfo map[string]ImageInfo     `json:"wall_info,omitempty"`
+	IconInfo map[string]IconThemeInfo `json:"icon_info,omitempty"`
+	// KittyColors holds each kitty theme's palette, KittyErrors the
+	// problems of broken ones.
+	KittyColors map[string]KittyPalette      `json:"kitty_colors,omitempty"`
+	KittyErrors map[string][]KittyColorError `json:"kitty_errors,omitempty"`
+}
+
+type ScanOptions struct {
+	Walls WallpaperScanOptions `json:"walls"`
+	// Full ignores the cache and scans everything.
+	Full bool `json:"-"`
+}
+
+// ScanCatalog rescans what changed since the cached catalog and saves the
+// result as the new cache.
+func ScanCatalog(opts ScanOptions) Catalog {
+	return ScanCatalogEach(opts, nil, nil)
+}
+
+// ScanProgress is how far the scan of one catalog section has got. Total
+// is 0 while the section is still counting.
+type ScanProgress struct {
+	Section     string
+	Done, Total int
+}
+
+// ScanSection is a finished catalog section: themes (Openbox and Gtk),
+// icons, kitty or walls. Only that section's fields of Catalog are set.
+// Errors are the directories that couldn't be read.
+type ScanSection struct {
+	Name    string
+	Catalog Catalog
+	Cached  bool
+	Errors  []error
+}
+
+// ScanSectionNames are the ScanSection names, in catalog order.
+var ScanSectionNames = []string{"themes", "icons", "kitty", "walls"}
+
+// ScanCatalogEach is ScanCatalog with the sections scanned concurrently.
+// progress and section, when not nil, are called from the scanning
+// goroutines as sections advance and finish.
+func ScanCatalogEach(opts ScanOptions, progress func(ScanProgress), section func(ScanSection)) Catalog {
+	var old catalogCache
+	if !opts.Full {
+		old, _ = loadCatalogCache()
+	}
+	results := make([]ScanSection, len(catalogSections))
+	sectionStamps := make([]stamps, len(catalogSections))
+	var wg sync.WaitGroup
+	for i, sec := range catalogSections {
+		wg.Add(1)
+		go func(i int, sec catalogSection) {
+			defer wg.Done()
+			res := ScanSection{Name: sec.name}
+			if prev, ok := old.Stamps[sec.name]; ok && sec.reuse(old, opts) && prev.fresh() {
+				sec.copy(&res.Catalog, old.Catalog)
+				res.Cached = true
+				sectionStamps[i] = prev
+			} else {
+				st := stamps{}
+				l := &scanLog{}
+				if progress != nil {
+					l.progress = func(done, total int) {
+						progress(ScanProgress{Section: sec.name, Done: done, Total: total})
+					}
+				}
+				sec.scan(&res.Catalog, opts, st, l)
+				res.Errors = l.errs
+				sectionStamps[i] = st
+			}
+			results[i] = res
+			if section != nil {
+				section(res)
+			}
+		}(i, sec)
+	}
+	wg.Wait()
+
+	c := catalogCache{Version: catalogCacheVersion, Options: opts, Stamps: map[string]stamps{}}
+	for i, sec := range catalogSections {
+		sec.copy(&c.Catalog, results[i].Catalog)
+		c.Stamps[sec.name] = sectionStamps[i]
+	}
+	c.Catalog.Styles = AvailableStyles(c.Catalog.Gtk, c.Catalog.Walls)
+	saveCatalogCache(c)
+	return c.Catalog
+}
+
+// A catalogSection is the part of the catalog one set of directories
+// decides. scan fills it in, stamping every path it reads before reading
+// it; copy takes it over from the cache.
+type catalogSection struct {
+	name  string
+	scan  func(c *Catalog, opts ScanOptions, st stamps, l *scanLog)
+	copy  func(dst *Catalog, src Catalog)
+	reuse func(old catalogCache, opts ScanOptions) bool
+}
+
+func always(catalogCache, ScanOptions) bool { return true }
+
+var catalogSections = []catalogSection{
+	{"themes", scanThemes, func(d *Catalog, s Catalog) { d.Openbox, d.Gtk = s.Openbox, s.Gtk }, always},
+	{"icons", scanIcons, func(d *Catalog, s Catalog) { d.Icons, d.IconInfo = s.Icons, s.IconInfo }, always},
+	{"kitty", scanKitty, func(d *Catalog, s Catalog) {
+		d.Kitty, d.KittyColors, d.KittyErrors = s.Kitty, s.KittyColors, s.KittyErrors
+	}, always},
+	{"walls", scanWalls, func(d *Catalog, s Catalog) { d.Walls, d.WallInfo = s.Walls, s.WallInfo }, func(old catalogCache, opts ScanOptions) bool {
+		return reflect.DeepEqual(old.Options.Walls, opts.Walls)
+	}},
+}
+
+// themePaths are the theme dirs, each theme in them and the subdirs whose
+// contents decide whether it is an Openbox or GTK theme.
+func themePaths(dirs []string, subdirs ...string) []string {
+	var out []string
+	for _, dir := range dirs {
+		out = append(out, dir)
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			if !dirEntryIsDir(dir, e) {
+				continue
+			}
+			out = append(out, filepath.Join(dir, e.Name()))
+			for _, sub := range subdirs {
+				out = append(out, filepath.Join(dir, e.Name(), sub))
+			}
+		}
+	}
+	return out
+}
+
+func scanThemes(c *Catalog, _ ScanOptions, st stamps, l *scanLog) {
+	st.add(themePaths(ThemeDirs(), "openbox-3", "gtk-3.0", "gtk-4.0")...)
+	c.Openbox = scanOpenboxThemes(l)
+	// Same dirs again; their errors are already in l.
+	c.Gtk = scanGtkThemes(nil)
+}
+
+func scanIcons(c *Catalog, _ ScanOptions, st stamps, l *scanLog) {
+	st.add(themePaths(IconDirs(), "index.theme")...)
+	c.Icons = scanIconThemes(l)
+	c.IconInfo = scanIconThemeInfo(l, c.Icons)
+}
+
+func scanKitty(c *Catalog, _ ScanOptions, st stamps, l *scanLog) {
+	dir := KittyThemesDir()
+	st.add(dir)
+	// Theme files are edited in place, which the dir's mtime misses.
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		st.add(filepath.Join(dir, e.Name()))
+	}
+	c.Kitty = scanKittyThemes(l)
+	c.KittyColors, c.KittyErrors = scanKittyColors(l, c.Kitty)
+}
+
+func scanWalls(c *Catalog, opts ScanOptions, st stamps, l *scanLog) {
+	c.Walls = scanWallpapers(l, opts.Walls, func(dir string) { st.add(dir) })
+	c.WallInfo = scanWallpaperInfo(l, c.Walls)
+}
+
+// Categories are the names the CLI and daemon use for each list.
+var Categories = []string{"style", "gtk", "icons", "labwc", "kitty", "walls"}
+
+func (c Catalog) Category(name string) ([]string, bool) {
+	switch name {
+	case "style", "styles":
+		return c.Styles, true
+	case "gtk":
+		return c.Gtk, true
+	case "icons", "icon":
+		return c.Icons, true
+	case "labwc", "openbox":
+		return c.Openbox, true
+	case "kitty":
+		return c.Kitty, true
+	case "walls", "wallpaper", "wallpapers":
+		return c.Walls, true
+	}
+	return nil, false
+}
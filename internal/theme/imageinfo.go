@@ -0,0 +1,118 @@
+package theme
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+)
+
+// ImageInfo is what the Walls panel shows about a file. Width and Height
+// are 0 for formats whose header we don't read (avif, jxl).
+type ImageInfo struct {
+	Width  int   `json:"w,omitempty"`
+	Height int   `json:"h,omitempty"`
+	Size   int64 `json:"size"`
+}
+
+// ReadImageInfo reads only the image header, never the pixels.
+func ReadImageInfo(path string) (ImageInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return ImageInfo{}, err
+	}
+	info := ImageInfo{Size: fi.Size()}
+
+	r := bufio.NewReader(f)
+	head, _ := r.Peek(32)
+	switch {
+	case len(head) >= 30 && bytes.Equal(head[0:4], []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WEBP")):
+		info.Width, info.Height = webpSize(head)
+	case len(head) >= 26 && bytes.Equal(head[0:2], []byte("BM")):
+		info.Width = int(int32(binary.LittleEndian.Uint32(head[18:22])))
+		info.Height = int(int32(binary.LittleEndian.Uint32(head[22:26])))
+		if info.Height < 0 {
+			info.Height = -info.Height // top-down bitmap
+		}
+	default:
+		if cfg, _, err := image.DecodeConfig(r); err == nil {
+			info.Width, info.Height = cfg.Width, cfg.Height
+		}
+	}
+	return info, nil
+}
+
+func webpSize(h []byte) (int, int) {
+	switch string(h[12:16]) {
+	case "VP8X":
+		w := int(h[24]) | int(h[25])<<8 | int(h[26])<<16
+		ht := int(h[27]) | int(h[28])<<8 | int(h[29])<<16
+		return w + 1, ht + 1
+	case "VP8L":
+		b := binary.LittleEndian.Uint32(h[21:25])
+		return int(b&0x3fff) + 1, int((b>>14)&0x3fff) + 1
+	case "VP8 ":
+		return int(binary.LittleEndian.Uint16(h[26:28]) & 0x3fff), int(binary.LittleEndian.Uint16(h[28:30]) & 0x3fff)
+	}
+	return 0, 0
+}
+
+var commonRatios = []struct {
+	name string
+	w, h float64
+}{
+	{"16:9", 16, 9}, {"16:10", 16, 10}, {"21:9", 64, 27}, {"32:9", 32, 9},
+	{"4:3", 4, 3}, {"3:2", 3, 2}, {"5:4", 5, 4}, {"1:1", 1, 1}, {"9:16", 9, 16},
+}
+
+// Aspect names the closest common ratio (3440x1440 is sold as 21:9), or
+// falls back to a decimal.
+func (i ImageInfo) Aspect() string {
+	if i.Width <= 0 || i.Height <= 0 {
+		return ""
+	}
+	r := float64(i.Width) / float64(i.Height)
+	for _, c := range commonRatios {
+		if math.Abs(r-c.w/c.h)/r < 0.03 {
+			return c.name
+		}
+	}
+	return fmt.Sprintf("%.2f:1", r)
+}
+
+// Describe is the one-line summary shown next to a wallpaper.
+func (i ImageInfo) Describe() string {
+	size := HumanSize(i.Size)
+	if i.Width <= 0 {
+		return size
+	}
+	return fmt.Sprintf("%dx%d · %s · %s", i.Width, i.Height, i.Aspect(), size)
+}
+
+// SmallerThan reports whether the image would be upscaled on a w×h output.
+func (i ImageInfo) SmallerThan(w, h int) bool {
+	return i.Width > 0 && (i.Width < w || i.Height < h)
+}
+
+func HumanSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%d KB", n>>10)
+	}
+	return fmt.Sprintf("%d B", n)
+}
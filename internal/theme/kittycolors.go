@@ -0,0 +1,427 @@
+package theme
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RGB is an 8-bit sRGB color.
+type RGB struct{ R, G, B uint8 }
+
+// Hex is the color as #rrggbb.
+func (c RGB) Hex() string { return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B) }
+
+// KittyColors is a kitty theme file read the way kitty reads it: includes
+// followed, every color syntax accepted, later settings winning.
+type KittyColors struct {
+	Colors map[string]RGB // by option name: foreground, color17, url_color, ...
+	// Special holds color options set to a keyword instead of a color:
+	// none, background, foreground or system.
+	Special map[string]string
+	// BackgroundOpacity is 1 unless the theme sets background_opacity.
+	BackgroundOpacity float64
+	// TransparentBackgrounds is transparent_background_colors.
+	TransparentBackgrounds []TransparentColor
+	Errors                 []KittyColorError
+}
+
+// TransparentColor is one color@opacity entry; Opacity is -1 when it
+// follows background_opacity.
+type TransparentColor struct {
+	Color   RGB
+	Opacity float64
+}
+
+// KittyColorError is a line of a theme file kitty would reject.
+type KittyColorError struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	Msg  string `json:"msg"`
+}
+
+func (e KittyColorError) Error() string {
+	if e.Line == 0 {
+		return filepath.Base(e.File) + ": " + e.Msg
+	}
+	return fmt.Sprintf("%s:%d: %s", filepath.Base(e.File), e.Line, e.Msg)
+}
+
+// KittyThemeFile finds the .conf file of a theme in KittyThemesDir,
+// matching the extension case-insensitively; "" if there is none.
+func KittyThemeFile(name string) string {
+	dir := KittyThemesDir()
+	entries, err := os.ReadDir(dir)
+	if err == nil {
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			n := e.Name()
+			if strings.ToLower(filepath.Ext(n)) == ".conf" && strings.TrimSuffix(n, filepath.Ext(n)) == name {
+				return filepath.Join(dir, n)
+			}
+		}
+	}
+	if p := filepath.Join(dir, name+".conf"); exists(p) {
+		return p
+	}
+	return ""
+}
+
+// LoadKittyColors parses a theme file and everything it includes.
+// Problems are collected in Errors; what could be read is still returned.
+func LoadKittyColors(path string) KittyColors {
+	kc := KittyColors{Colors: map[string]RGB{}, Special: map[string]string{}, BackgroundOpacity: 1}
+	kc.load(path, map[string]bool{}, KittyColorError{})
+	return kc
+}
+
+// scanKittyColors reads every theme, returning its palette and, for the
+// broken ones, the problems.
+func scanKittyColors(l *scanLog, names []string) (map[string]KittyPalette, map[string][]KittyColorError) {
+	palettes := make(map[string]KittyPalette, len(names))
+	errs := map[string][]KittyColorError{}
+	for i, n := range names {
+		l.step(i+1, len(names))
+		p := KittyThemeFile(n)
+		if p == "" {
+			continue
+		}
+		kc := LoadKittyColors(p)
+		palettes[n] = kc.Palette()
+		if len(kc.Errors) > 0 {
+			errs[n] = kc.Errors
+		}
+	}
+	return palettes, errs
+}
+
+// KittyPalette is the part of a theme worth keeping in the catalog:
+// foreground, background and color0-15, as #rrggbb.
+type KittyPalette map[string]string
+
+func (kc KittyColors) Palette() KittyPalette {
+	out := KittyPalette{}
+	for _, k := range kittyPaletteKeys {
+		if c, ok := kc.Colors[k]; ok {
+			out[k] = c.Hex()
+		}
+	}
+	return out
+}
+
+var kittyPaletteKeys = []string{
+	"foreground", "background",
+	"color0", "color1", "color2", "color3", "color4", "color5", "color6", "color7",
+	"color8", "color9", "color10", "color11", "color12", "color13", "color14", "color15",
+}
+
+const maxKittyIncludeDepth = 16
+
+// load reads one file; from is the include line that led here, for errors.
+func (kc *KittyColors) load(path string, seen map[string]bool, from KittyColorError) {
+	abs, _ := filepath.Abs(path)
+	if seen[abs] {
+		kc.fail(from, "include loop through "+filepath.Base(path))
+		return
+	}
+	if len(seen) >= maxKittyIncludeDepth {
+		kc.fail(from, "includes nested too deeply")
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if from.File == "" {
+			from.File = path
+		}
+		kc.fail(from, err.Error())
+		return
+	}
+	defer f.Close()
+	seen[abs] = true
+	defer delete(seen, abs)
+
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value := line, ""
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			key, value = line[:i], strings.TrimSpace(line[i:])
+		}
+		at := KittyColorError{File: path, Line: n}
+		switch {
+		case key == "include":
+			kc.include(path, value, false, seen, at)
+		case key == "globinclude":
+			kc.include(path, value, true, seen, at)
+		case key == "envinclude":
+			// Pulls config from the environment at kitty's startup;
+			// nothing a theme preview can follow.
+		case key == "background_opacity":
+			v, err := strconv.ParseFloat(value, 64)
+			if err != nil || v < 0 || v > 1 {
+				kc.fail(at, fmt.Sprintf("background_opacity %q: want a number from 0 to 1", value))
+				continue
+			}
+			kc.BackgroundOpacity = v
+		case key == "transparent_background_colors":
+			kc.transparent(value, at)
+		case IsKittyColorKey(key):
+			kc.color(key, value, at)
+		}
+	}
+	if err := s.Err(); err != nil {
+		kc.fail(KittyColorError{File: path}, err.Error())
+	}
+}
+
+func (kc *KittyColors) fail(at KittyColorError, msg string) {
+	at.Msg = msg
+	kc.Errors = append(kc.Errors, at)
+}
+
+// include resolves value against the including file's directory, then
+// the themes dir, then kitty's config dir.
+func (kc *KittyColors) include(from, value string, glob bool, seen map[string]bool, at KittyColorError) {
+	if value == "" {
+		kc.fail(at, "include without a file")
+		return
+	}
+	value = os.ExpandEnv(value)
+	if strings.HasPrefix(value, "~/") {
+		value = filepath.Join(HomeDir(), value[2:])
+	}
+	var candidates []string
+	if filepath.IsAbs(value) {
+		candidates = []string{value}
+	} else {
+		for _, dir := range []string{filepath.Dir(from), KittyThemesDir(), filepath.Dir(KittyConfPath())} {
+			candidates = append(candidates, filepath.Join(dir, value))
+		}
+	}
+	for _, c := range candidates {
+		if glob {
+			matches, err := filepath.Glob(c)
+			if err != nil {
+				kc.fail(at, fmt.Sprintf("globinclude %q: %v", value, err))
+				return
+			}
+			if len(matches) == 0 {
+				continue
+			}
+			for _, m := range matches {
+				kc.load(m, seen, at)
+			}
+			return
+		}
+		if exists(c) {
+			kc.load(c, seen, at)
+			return
+		}
+	}
+	if !glob {
+		kc.fail(at, fmt.Sprintf("included file %q not found", value))
+	}
+}
+
+func (kc *KittyColors) color(key, value string, at KittyColorError) {
+	v := strings.ToLower(strings.TrimSpace(value))
+	if kittyKeywordAllowed(key, v) {
+		delete(kc.Colors, key)
+		kc.Special[key] = v
+		return
+	}
+	c, ok := ParseKittyColor(v)
+	if !ok {
+		kc.fail(at, fmt.Sprintf("%s: %q is not a color", key, value))
+		return
+	}
+	delete(kc.Special, key)
+	kc.Colors[key] = c
+}
+
+func (kc *KittyColors) transparent(value string, at KittyColorError) {
+	kc.TransparentBackgrounds = nil
+	for _, entry := range strings.Fields(value) {
+		spec, op, hasOp := strings.Cut(entry, "@")
+		c, ok := ParseKittyColor(strings.ToLower(spec))
+		if !ok {
+			kc.fail(at, fmt.Sprintf("transparent_background_colors: %q is not a color", spec))
+			continue
+		}
+		t := TransparentColor{Color: c, Opacity: -1}
+		if hasOp {
+			v, err := strconv.ParseFloat(op, 64)
+			if err != nil || v < 0 || v > 1 {
+				kc.fail(at, fmt.Sprintf("transparent_background_colors: opacity %q: want 0 to 1", op))
+				continue
+			}
+			t.Opacity = v
+		}
+		kc.TransparentBackgrounds = append(kc.TransparentBackgrounds, t)
+	}
+	if len(kc.TransparentBackgrounds) > 7 {
+		kc.fail(at, "transparent_background_colors: at most 7 colors")
+		kc.TransparentBackgrounds = kc.TransparentBackgrounds[:7]
+	}
+}
+
+var kittyColorKeys = map[string]bool{
+	"foreground": true, "background": true,
+	"selection_foreground": true, "selection_background": true,
+	"cursor": true, "cursor_text_color": true, "cursor_trail_color": true,
+	"active_border_color": true, "inactive_border_color": true, "bell_border_color": true,
+	"url_color": true, "visual_bell_color": true,
+	"active_tab_foreground": true, "active_tab_background": true,
+	"inactive_tab_foreground": true, "inactive_tab_background": true,
+	"tab_bar_background": true, "tab_bar_margin_color": true,
+	"macos_titlebar_color": true, "wayland_titlebar_color": true,
+	"mark1_foreground": true, "mark1_background": true,
+	"mark2_foreground": true, "mark2_background": true,
+	"mark3_foreground": true, "mark3_background": true,
+}
+
+// IsKittyColorKey reports whether key is a kitty option that takes a color.
+func IsKittyColorKey(key string) bool {
+	if kittyColorKeys[key] {
+		return true
+	}
+	if n, ok := strings.CutPrefix(key, "color"); ok {
+		i, err := strconv.Atoi(n)
+		return err == nil && i >= 0 && i <= 255 && strconv.Itoa(i) == n
+	}
+	return false
+}
+
+// kittyKeywordAllowed reports whether key accepts v instead of a color.
+func kittyKeywordAllowed(key, v string) bool {
+	switch v {
+	case "none":
+		return key != "foreground" && key != "background" && !strings.HasPrefix(key, "color") &&
+			!strings.HasPrefix(key, "mark")
+	case "background":
+		return key == "cursor_text_color" || key == "macos_titlebar_color" || key == "wayland_titlebar_color"
+	case "system":
+		return key == "macos_titlebar_color" || key == "wayland_titlebar_color"
+	}
+	return false
+}
+
+// ParseKittyColor accepts what kitty does: #RGB, #RRGGBB, #RRRGGGBBB,
+// #RRRRGGGGBBBB, rgb:R/G/B with 1-4 hex digits each, and X11 color names.
+func ParseKittyColor(s string) (RGB, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		n := len(hex)
+		if n != 3 && n != 6 && n != 9 && n != 12 {
+			return RGB{}, false
+		}
+		w := n / 3
+		var ch [3]uint8
+		for i := range ch {
+			v, ok := scaleHex(hex[i*w : (i+1)*w])
+			if !ok {
+				return RGB{}, false
+			}
+			ch[i] = v
+		}
+		return RGB{ch[0], ch[1], ch[2]}, true
+	}
+	if spec, ok := strings.CutPrefix(s, "rgb:"); ok {
+		parts := strings.Split(spec, "/")
+		if len(parts) != 3 {
+			return RGB{}, false
+		}
+		var ch [3]uint8
+		for i, p := range parts {
+			if len(p) < 1 || len(p) > 4 {
+				return RGB{}, false
+			}
+			v, ok := scaleHex(p)
+			if !ok {
+				return RGB{}, false
+			}
+			ch[i] = v
+		}
+		return RGB{ch[0], ch[1], ch[2]}, true
+	}
+	return namedColor(strings.ReplaceAll(s, " ", ""))
+}
+
+// scaleHex maps 1-4 hex digits onto 0-255.
+func scaleHex(h string) (uint8, bool) {
+	v, err := strconv.ParseUint(h, 16, 16)
+	if err != nil {
+		return 0, false
+	}
+	max := uint64(1)<<(4*len(h)) - 1
+	return uint8((v*255 + max/2) / max), true
+}
+
+func namedColor(name string) (RGB, bool) {
+	for _, prefix := range []string{"gray", "grey"} {
+		if n, ok := strings.CutPrefix(name, prefix); ok && n != "" {
+			i, err := strconv.Atoi(n)
+			if err != nil || i < 0 || i > 100 {
+				return RGB{}, false
+			}
+			v := uint8((i*255 + 50) / 100)
+			return RGB{v, v, v}, true
+		}
+	}
+	v, ok := x11Colors[name]
+	if !ok {
+		return RGB{}, false
+	}
+	return RGB{uint8(v >> 16), uint8(v >> 8), uint8(v)}, true
+}
+
+// x11Colors are the X11 names kitty accepts, spaces removed. X11's gray
+// and green differ from CSS's.
+var x11Colors = map[string]uint32{
+	"aliceblue": 0xf0f8ff, "antiquewhite": 0xfaebd7, "aqua": 0x00ffff, "aquamarine": 0x7fffd4,
+	"azure": 0xf0ffff, "beige": 0xf5f5dc, "bisque": 0xffe4c4, "black": 0x000000,
+	"blanchedalmond": 0xffebcd, "blue": 0x0000ff, "blueviolet": 0x8a2be2, "brown": 0xa52a2a,
+	"burlywood": 0xdeb887, "cadetblue": 0x5f9ea0, "chartreuse": 0x7fff00, "chocolate": 0xd2691e,
+	"coral": 0xff7f50, "cornflowerblue": 0x6495ed, "cornsilk": 0xfff8dc, "crimson": 0xdc143c,
+	"cyan": 0x00ffff, "darkblue": 0x00008b, "darkcyan": 0x008b8b, "darkgoldenrod": 0xb8860b,
+	"darkgray": 0xa9a9a9, "darkgreen": 0x006400, "darkgrey": 0xa9a9a9, "darkkhaki": 0xbdb76b,
+	"darkmagenta": 0x8b008b, "darkolivegreen": 0x556b2f, "darkorange": 0xff8c00, "darkorchid": 0x9932cc,
+	"darkred": 0x8b0000, "darksalmon": 0xe9967a, "darkseagreen": 0x8fbc8f, "darkslateblue": 0x483d8b,
+	"darkslategray": 0x2f4f4f, "darkslategrey": 0x2f4f4f, "darkturquoise": 0x00ced1, "darkviolet": 0x9400d3,
+	"deeppink": 0xff1493, "deepskyblue": 0x00bfff, "dimgray": 0x696969, "dimgrey": 0x696969,
+	"dodgerblue": 0x1e90ff, "firebrick": 0xb22222, "floralwhite": 0xfffaf0, "forestgreen": 0x228b22,
+	"fuchsia": 0xff00ff, "gainsboro": 0xdcdcdc, "ghostwhite": 0xf8f8ff, "gold": 0xffd700,
+	"goldenrod": 0xdaa520, "gray": 0xbebebe, "green": 0x00ff00, "greenyellow": 0xadff2f,
+	"grey": 0xbebebe, "honeydew": 0xf0fff0, "hotpink": 0xff69b4, "indianred": 0xcd5c5c,
+	"indigo": 0x4b0082, "ivory": 0xfffff0, "khaki": 0xf0e68c, "lavender": 0xe6e6fa,
+	"lavenderblush": 0xfff0f5, "lawngreen": 0x7cfc00, "lemonchiffon": 0xfffacd, "lightblue": 0xadd8e6,
+	"lightcoral": 0xf08080, "lightcyan": 0xe0ffff, "lightgoldenrod": 0xeedd82, "lightgoldenrodyellow": 0xfafad2,
+	"lightgray": 0xd3d3d3, "lightgreen": 0x90ee90, "lightgrey": 0xd3d3d3, "lightpink": 0xffb6c1,
+	"lightsalmon": 0xffa07a, "lightseagreen": 0x20b2aa, "lightskyblue": 0x87cefa, "lightslateblue": 0x8470ff,
+	"lightslategray": 0x778899, "lightslategrey": 0x778899, "lightsteelblue": 0xb0c4de, "lightyellow": 0xffffe0,
+	"lime": 0x00ff00, "limegreen": 0x32cd32, "linen": 0xfaf0e6, "magenta": 0xff00ff,
+	"maroon": 0xb03060, "mediumaquamarine": 0x66cdaa, "mediumblue": 0x0000cd, "mediumorchid": 0xba55d3,
+	"mediumpurple": 0x9370db, "mediumseagreen": 0x3cb371, "mediumslateblue": 0x7b68ee, "mediumspringgreen": 0x00fa9a,
+	"mediumturquoise": 0x48d1cc, "mediumvioletred": 0xc71585, "midnightblue": 0x191970, "mintcream": 0xf5fffa,
+	"mistyrose": 0xffe4e1, "moccasin": 0xffe4b5, "navajowhite": 0xffdead, "navy": 0x000080,
+	"navyblue": 0x000080, "oldlace": 0xfdf5e6, "olive": 0x808000, "olivedrab": 0x6b8e23,
+	"orange": 0xffa500, "orangered": 0xff4500, "orchid": 0xda70d6, "palegoldenrod": 0xeee8aa,
+	"palegreen": 0x98fb98, "paleturquoise": 0xafeeee, "palevioletred": 0xdb7093, "papayawhip": 0xffefd5,
+	"peachpuff": 0xffdab9, "peru": 0xcd853f, "pink": 0xffc0cb, "plum": 0xdda0dd,
+	"powderblue": 0xb0e0e6, "purple": 0xa020f0, "rebeccapurple": 0x663399, "red": 0xff0000,
+	"rosybrown": 0xbc8f8f, "royalblue": 0x4169e1, "saddlebrown": 0x8b4513, "salmon": 0xfa8072,
+	"sandybrown": 0xf4a460, "seagreen": 0x2e8b57, "seashell": 0xfff5ee, "sienna": 0xa0522d,
+	"silver": 0xc0c0c0, "skyblue": 0x87ceeb, "slateblue": 0x6a5acd, "slategray": 0x708090,
+	"slategrey": 0x708090, "snow": 0xfffafa, "springgreen": 0x00ff7f, "steelblue": 0x4682b4,
+	"tan": 0xd2b48c, "teal": 0x008080, "thistle": 0xd8bfd8, "tomato": 0xff6347,
+	"turquoise": 0x40e0d0, "violet": 0xee82ee, "violetred": 0xd02090, "wheat": 0xf5deb3,
+	"white": 0xffffff, "whitesmoke": 0xf5f5f5, "yellow": 0xffff00, "yellowgreen": 0x9acd32,
+}
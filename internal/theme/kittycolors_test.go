@@ -0,0 +1,159 @@
+package theme
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseKittyColor(t *testing.T) {
+	tests := []struct {
+		in   string
+		want RGB
+		ok   bool
+	}{
+		{"#123", RGB{0x11, 0x22, 0x33}, true},
+		{"#1a2B3c", RGB{0x1a, 0x2b, 0x3c}, true},
+		{"#fff000fff", RGB{0xff, 0x00, 0xff}, true},
+		{"#ffff80800000", RGB{0xff, 0x80, 0x00}, true},
+		{"  #ABCDEF ", RGB{0xab, 0xcd, 0xef}, true},
+		{"rgb:0/8/f", RGB{0x00, 0x88, 0xff}, true},
+		{"rgb:ff/80/00", RGB{0xff, 0x80, 0x00}, true},
+		{"rgb:ffff/8080/0", RGB{0xff, 0x80, 0x00}, true},
+		{"red", RGB{0xff, 0x00, 0x00}, true},
+		{"Dark Slate Gray", RGB{0x2f, 0x4f, 0x4f}, true},
+		{"gray", RGB{0xbe, 0xbe, 0xbe}, true},
+		{"green", RGB{0x00, 0xff, 0x00}, true},
+		{"grey50", RGB{0x80, 0x80, 0x80}, true},
+		{"gray100", RGB{0xff, 0xff, 0xff}, true},
+		{"gray101", RGB{}, false},
+		{"#12345", RGB{}, false},
+		{"#ggg", RGB{}, false},
+		{"123456", RGB{}, false},
+		{"rgb:1/2", RGB{}, false},
+		{"rgb:12345/0/0", RGB{}, false},
+		{"rgb://", RGB{}, false},
+		{"none", RGB{}, false},
+		{"notacolor", RGB{}, false},
+		{"", RGB{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, ok := ParseKittyColor(tt.in)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("ParseKittyColor(%q) = %v, %v; want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// Each case loads testdata/kitty/<file>. HOME points at testdata/kitty/home
+// so includes can fall back to its kitty themes dir.
+var kittyColorsTests = []struct {
+	file        string
+	colors      map[string]string
+	special     map[string]string
+	opacity     float64
+	transparent []TransparentColor
+	errs        []string
+}{
+	{
+		file: "basic.conf",
+		colors: map[string]string{
+			"foreground": "#c0c0c0", "background": "#112233",
+			"color0": "#000000", "color1": "#aa0000", "color2": "#0088ff", "color3": "#ff8000",
+			"color4": "#ff8000", "color5": "#ff00ff", "color6": "#2f4f4f", "color7": "#2f4f4f",
+			"color8": "#808080", "selection_background": "#444444", "url_color": "#abcdef",
+		},
+		special: map[string]string{"cursor_text_color": "background", "selection_foreground": "none"},
+		opacity: 0.9,
+	},
+	{
+		file: "include.conf",
+		colors: map[string]string{
+			"foreground": "#dddddd", "background": "#111111",
+			"color1": "#010101", "color2": "#00ff00", "color3": "#0000ff", "color4": "#abcdef",
+		},
+		opacity: 1,
+	},
+	{
+		file:    "loop-a.conf",
+		colors:  map[string]string{"foreground": "#aaaaaa", "background": "#bbbbbb"},
+		opacity: 1,
+		errs:    []string{"loop-b.conf:2: include loop through loop-a.conf"},
+	},
+	{
+		file:        "malformed.conf",
+		colors:      map[string]string{"color2": "#00ff00"},
+		opacity:     1,
+		transparent: []TransparentColor{{Color: RGB{0x11, 0x11, 0x11}, Opacity: 0.5}},
+		errs: []string{
+			`malformed.conf:1: foreground: "nope" is not a color`,
+			`malformed.conf:2: background: "#12345" is not a color`,
+			`malformed.conf:3: color1: "rgb:1/2" is not a color`,
+			`malformed.conf:5: foreground: "none" is not a color`,
+			`malformed.conf:7: background_opacity "2": want a number from 0 to 1`,
+			`malformed.conf:8: include without a file`,
+			`malformed.conf:9: included file "missing.conf" not found`,
+			`malformed.conf:11: transparent_background_colors: "#zzz" is not a color`,
+			`malformed.conf:11: transparent_background_colors: opacity "3": want 0 to 1`,
+		},
+	},
+	{
+		file:    "absent.conf",
+		colors:  map[string]string{},
+		opacity: 1,
+		errs:    []string{"absent.conf: open testdata/kitty/absent.conf: no such file or directory"},
+	},
+}
+
+func TestLoadKittyColors(t *testing.T) {
+	home, err := filepath.Abs(filepath.Join("testdata", "kitty", "home"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HOME", home)
+	for _, tt := range kittyColorsTests {
+		t.Run(tt.file, func(t *testing.T) {
+			kc := LoadKittyColors(filepath.Join("testdata", "kitty", tt.file))
+			colors := map[string]string{}
+			for k, c := range kc.Colors {
+				colors[k] = c.Hex()
+			}
+			if !reflect.DeepEqual(colors, tt.colors) {
+				t.Errorf("colors = %v, want %v", colors, tt.colors)
+			}
+			special := tt.special
+			if special == nil {
+				special = map[string]string{}
+			}
+			if !reflect.DeepEqual(kc.Special, special) {
+				t.Errorf("special = %v, want %v", kc.Special, special)
+			}
+			if kc.BackgroundOpacity != tt.opacity {
+				t.Errorf("background opacity = %v, want %v", kc.BackgroundOpacity, tt.opacity)
+			}
+			if !reflect.DeepEqual(kc.TransparentBackgrounds, tt.transparent) {
+				t.Errorf("transparent backgrounds = %v, want %v", kc.TransparentBackgrounds, tt.transparent)
+			}
+			var errs []string
+			for _, e := range kc.Errors {
+				errs = append(errs, e.Error())
+			}
+			if !reflect.DeepEqual(errs, tt.errs) {
+				t.Errorf("errors:\n%q\nwant:\n%q", errs, tt.errs)
+			}
+		})
+	}
+}
+
+func TestKittyPalette(t *testing.T) {
+	kc := LoadKittyColors(filepath.Join("testdata", "kitty", "basic.conf"))
+	p := kc.Palette()
+	if len(p) != 11 {
+		t.Errorf("palette has %d colors, want 11: %v", len(p), p)
+	}
+	if p["color1"] != "#aa0000" || p["url_color"] != "" {
+		t.Errorf("palette = %v", p)
+	}
+}
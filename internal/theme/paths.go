@@ -1,6 +1,7 @@
 package theme
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 )
@@ -25,8 +26,12 @@ func ThemeDirs() []string {
 }
 
 func Gtk4SettingsPath() string {
+	return filepath.Join(Gtk4ConfigDir(), "settings.ini")
+}
+
+func Gtk4ConfigDir() string {
 	h := HomeDir()
-	return filepath.Join(h, ".config/gtk-4.0/settings.ini")
+	return filepath.Join(h, ".config/gtk-4.0")
 }
 
 func IconDirs() []string {
@@ -44,6 +49,18 @@ func KittyThemesDir() string {
 	return filepath.Join(h, ".config/kitty/themes")
 }
 
+func KittyConfPath() string {
+	h := HomeDir()
+	return filepath.Join(h, ".config/kitty/kitty.conf")
+}
+
+// KittyCurrentThemePath is the file `kitten themes` writes and includes
+// from kitty.conf.
+func KittyCurrentThemePath() string {
+	h := HomeDir()
+	return filepath.Join(h, ".config/kitty/current-theme.conf")
+}
+
 func LabwcRcPath() string {
 	h := HomeDir()
 	return filepath.Join(h, ".config/labwc/rc.xml")
@@ -59,7 +76,108 @@ func FuzzelIniPath() string {
 	return filepath.Join(h, ".config/fuzzel/fuzzel.ini")
 }
 
+func RofiConfigPath() string {
+	h := HomeDir()
+	return filepath.Join(h, ".config/rofi/config.rasi")
+}
+
+func WofiStylePath() string {
+	h := HomeDir()
+	return filepath.Join(h, ".config/wofi/style.css")
+}
+
+func TofiConfigPath() string {
+	h := HomeDir()
+	return filepath.Join(h, ".config/tofi/config")
+}
+
 func WallpaperDir() string {
 	h := HomeDir()
 	return filepath.Join(h, "Pictures/walls")
 }
+
+// xdgDir returns $env if set to an absolute path, otherwise ~/fallback.
+func xdgDir(env, fallback string) string {
+	if v := os.Getenv(env); v != "" && filepath.IsAbs(v) {
+		return v
+	}
+	return filepath.Join(HomeDir(), fallback)
+}
+
+func ConfigDir() string {
+	return filepath.Join(xdgDir("XDG_CONFIG_HOME", ".config"), "labwcchanger-tui")
+}
+
+func ConfigPath() string {
+	return filepath.Join(ConfigDir(), "config.json")
+}
+
+// SocketPath is where the daemon listens. Falls back to /tmp when there is
+// no runtime dir (e.g. started outside a login session).
+func SocketPath() string {
+	if d := os.Getenv("XDG_RUNTIME_DIR"); d != "" && filepath.IsAbs(d) {
+		return filepath.Join(d, "labwcchanger-tui.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("labwcchanger-tui-%d.sock", os.Getuid()))
+}
+
+func MarksPath() string {
+	return filepath.Join(ConfigDir(), "marks.json")
+}
+
+func StateDir() string {
+	return filepath.Join(xdgDir("XDG_STATE_HOME", ".local/state"), "labwcchanger-tui")
+}
+
+func CacheDir() string {
+	return filepath.Join(xdgDir("XDG_CACHE_HOME", ".cache"), "labwcchanger-tui")
+}
+
+func CatalogCachePath() string {
+	return filepath.Join(CacheDir(), "catalog.json")
+}
+
+func HistoryPath() string {
+	return filepath.Join(StateDir(), "history.jsonl")
+}
+
+// Gtk4LinksPath records the ~/.config/gtk-4.0 links Apply made, so only
+// those are ever removed.
+func Gtk4LinksPath() string {
+	return filepath.Join(StateDir(), "gtk4-links.json")
+}
+
+// FlatpakEnvPath records the Flatpak override variables Apply set and the
+// filesystems entries it added, so turning FlatpakOverrides off removes
+// only those.
+func FlatpakEnvPath() string {
+	return filepath.Join(StateDir(), "flatpak-env.json")
+}
+
+// LabwcThemercOverridePath is read by labwc on top of the theme's themerc.
+func LabwcThemercOverridePath() string {
+	h := HomeDir()
+	return filepath.Join(h, ".config/labwc/themerc-override")
+}
+
+// UserThemesDir is where generated themes are written; it is one of
+// ThemeDirs.
+func UserThemesDir() string {
+	h := HomeDir()
+	return filepath.Join(h, ".local/share/themes")
+}
+
+func Gtk4CssPath() string {
+	return filepath.Join(Gtk4ConfigDir(), "gtk.css")
+}
+
+func Gtk3CssPath() string {
+	h := HomeDir()
+	return filepath.Join(h, ".config/gtk-3.0/gtk.css")
+}
+
+// FlatpakOverridesPath holds `flatpak override --user` settings for all apps.
+func FlatpakOverridesPath() string {
+	h := HomeDir()
+	return filepath.Join(h, ".local/share/flatpak/overrides/global")
+}
@@ -1,6 +1,7 @@
 package theme
 
 import (
+	"bufio"
 	"os"
 	"path/filepath"
 	"sort"
@@ -23,9 +24,9 @@ func dirEntryIsDir(parent string, e os.DirEntry) bool {
 }
 
 
-func ScanKittyThemes() []string {
+func scanKittyThemes(l *scanLog) []string {
 	dir := KittyThemesDir()
-	entries, err := os.ReadDir(dir)
+	entries, err := l.readDir(dir)
 	if err != nil {
 		return []string{}
 	}
@@ -51,10 +52,10 @@ func ScanKittyThemes() []string {
 	return out
 }
 
-func ScanOpenboxThemes() []string {
+func scanOpenboxThemes(l *scanLog) []string {
 	set := map[string]struct{}{"GTK": {}}
 	for _, dir := range ThemeDirs() {
-		entries, err := os.ReadDir(dir)
+		entries, err := l.readDir(dir)
 		if err != nil {
 			continue
 		}
@@ -75,10 +76,10 @@ func ScanOpenboxThemes() []string {
 	return out
 }
 
-func ScanGtkThemes() []string {
+func scanGtkThemes(l *scanLog) []string {
 	set := map[string]struct{}{}
 	for _, dir := range ThemeDirs() {
-		entries, err := os.ReadDir(dir)
+		entries, err := l.readDir(dir)
 		if err != nil {
 			continue
 		}
@@ -102,10 +103,10 @@ func ScanGtkThemes() []string {
 	return out
 }
 
-func ScanIconThemes() []string {
+func scanIconThemes(l *scanLog) []string {
 	set := map[string]struct{}{}
 	for _, dir := range IconDirs() {
-		entries, err := os.ReadDir(dir)
+		entries, err := l.readDir(dir)
 		if err != nil {
 			continue
 		}
@@ -129,29 +130,171 @@ func ScanIconThemes() []string {
 	return out
 }
 
-func ScanWallpapers() []string {
-	dir := WallpaperDir()
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return []string{}
+// DefaultWallpaperExts are always scanned; backends add the rest.
+var DefaultWallpaperExts = []string{".jpg", ".jpeg", ".png", ".webp"}
+
+type WallpaperScanOptions struct {
+	// Extensions to accept, lowercase with dot. Empty means DefaultWallpaperExts.
+	Extensions []string `json:"extensions,omitempty"`
+	// Exclude holds filepath.Match patterns, tried against both the path
+	// relative to WallpaperDir and the bare name. A matching directory is
+	// skipped entirely.
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+func (o WallpaperScanOptions) excluded(rel, name string) bool {
+	for _, pat := range o.Exclude {
+		if ok, _ := filepath.Match(pat, rel); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pat, name); ok {
+			return true
+		}
 	}
+	return false
+}
+
+// scanWallpapers walks WallpaperDir recursively and returns paths relative
+// to it ("nature/forest.jpg"), following symlinked directories once. onDir
+// is called with every directory before it is read.
+func scanWallpapers(l *scanLog, opts WallpaperScanOptions, onDir func(dir string)) []string {
+	exts := opts.Extensions
+	if len(exts) == 0 {
+		exts = DefaultWallpaperExts
+	}
+	accept := map[string]bool{}
+	for _, e := range exts {
+		accept[strings.ToLower(e)] = true
+	}
+
+	root := WallpaperDir()
 	out := []string{}
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
+	seen := map[string]bool{}
+	var walk func(dir, rel string)
+	walk = func(dir, rel string) {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			if seen[real] {
+				return
+			}
+			seen[real] = true
 		}
-		name := e.Name()
-		ext := strings.ToLower(filepath.Ext(name))
-		switch ext {
-		case ".jpg", ".jpeg", ".png", ".webp":
-			out = append(out, name)
+		onDir(dir)
+		entries, err := l.readDir(dir)
+		if err != nil {
+			return
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if strings.HasPrefix(name, ".") {
+				continue
+			}
+			relPath := filepath.Join(rel, name)
+			if opts.excluded(relPath, name) {
+				continue
+			}
+			if dirEntryIsDir(dir, e) {
+				walk(filepath.Join(dir, name), relPath)
+				continue
+			}
+			if accept[strings.ToLower(filepath.Ext(name))] {
+				out = append(out, relPath)
+				l.step(len(out), 0)
+			}
 		}
 	}
+	walk(root, "")
 	sort.Strings(out)
 	return out
 }
 
+// scanWallpaperInfo reads the header of every wallpaper. Unreadable files
+// are left out.
+func scanWallpaperInfo(l *scanLog, walls []string) map[string]ImageInfo {
+	out := make(map[string]ImageInfo, len(walls))
+	for i, w := range walls {
+		l.step(i+1, len(walls))
+		if info, err := ReadImageInfo(filepath.Join(WallpaperDir(), w)); err == nil {
+			out[w] = info
+		}
+	}
+	return out
+}
+
+// Gtk4Dir returns the gtk-4.0 directory of the named GTK theme from the
+// first theme dir that has one, or "".
+func Gtk4Dir(name string) string {
+	for _, dir := range ThemeDirs() {
+		p := filepath.Join(dir, name, "gtk-4.0")
+		if exists(filepath.Join(p, "gtk.css")) {
+			return p
+		}
+	}
+	return ""
+}
+
 func exists(p string) bool {
 	_, err := os.Stat(p)
 	return err == nil
 }
+
+// IconThemeInfo is the [Icon Theme] section of an index.theme.
+type IconThemeInfo struct {
+	Name     string   `json:"name,omitempty"`
+	Comment  string   `json:"comment,omitempty"`
+	Inherits []string `json:"inherits,omitempty"`
+}
+
+// scanIconThemeInfo reads the index.theme of each icon theme, from the
+// first icon dir that has one.
+func scanIconThemeInfo(l *scanLog, names []string) map[string]IconThemeInfo {
+	out := make(map[string]IconThemeInfo, len(names))
+	for i, name := range names {
+		l.step(i+1, len(names))
+		for _, dir := range IconDirs() {
+			if info, err := ReadIconThemeInfo(filepath.Join(dir, name, "index.theme")); err == nil {
+				out[name] = info
+				break
+			}
+		}
+	}
+	return out
+}
+
+func ReadIconThemeInfo(path string) (IconThemeInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return IconThemeInfo{}, err
+	}
+	defer f.Close()
+	var info IconThemeInfo
+	in := false
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		t := strings.TrimSpace(sc.Text())
+		if strings.HasPrefix(t, "[") {
+			if in {
+				break
+			}
+			in = t == "[Icon Theme]"
+			continue
+		}
+		k, v, ok := strings.Cut(t, "=")
+		if !in || !ok {
+			continue
+		}
+		v = strings.TrimSpace(v)
+		switch strings.TrimSpace(k) {
+		case "Name":
+			info.Name = v
+		case "Comment":
+			info.Comment = v
+		case "Inherits":
+			for _, i := range strings.Split(v, ",") {
+				if i = strings.TrimSpace(i); i != "" {
+					info.Inherits = append(info.Inherits, i)
+				}
+			}
+		}
+	}
+	return info, sc.Err()
+}
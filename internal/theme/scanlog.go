@@ -0,0 +1,32 @@
+package theme
+
+import (
+	"os"
+	"sync"
+)
+
+// scanLog collects the directories a scan couldn't read and passes its
+// progress on. A nil *scanLog does neither.
+type scanLog struct {
+	mu       sync.Mutex
+	errs     []error
+	progress func(done, total int)
+}
+
+// readDir is os.ReadDir, noting errors other than a missing directory.
+func (l *scanLog) readDir(dir string) ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !os.IsNotExist(err) && l != nil {
+		l.mu.Lock()
+		l.errs = append(l.errs, err)
+		l.mu.Unlock()
+	}
+	return entries, err
+}
+
+// step reports done items of total, 0 while the total isn't known yet.
+func (l *scanLog) step(done, total int) {
+	if l != nil && l.progress != nil {
+		l.progress(done, total)
+	}
+}
@@ -58,59 +58,54 @@ func AvailableStyles(gtkThemes, wallpapers []string) []string {
 	return out
 }
 
-func ApplyStyle(style string, openbox, gtk, icons, kitty, walls []string) (selOpenbox, selGtk, selIcon, selKitty, selWall string) {
-	keywords := styleApplyKeywords[style]
-	if len(keywords) == 0 {
-		keywords = []string{strings.ToLower(style)}
-	}
+// ApplyStyle picks the best match for style in each list. With palette
+// set, a style no Openbox theme matches gets the one generated from its
+// kitty theme; otherwise the Openbox choice is left empty.
+func ApplyStyle(style string, palette bool, openbox, gtk, icons, kitty, walls []string) (selOpenbox, selGtk, selIcon, selKitty, selWall string) {
+	keywords := styleKeywords(style)
 	selOpenbox = BestMatch(openbox, keywords)
 	selGtk = BestMatch(gtk, keywords)
 	selIcon = BestMatch(icons, keywords)
 	selKitty = BestMatch(kitty, keywords)
 	selWall = BestMatch(walls, keywords)
+	if palette && selOpenbox == "" && selKitty != "" {
+		// Apply generates this theme from the kitty palette.
+		selOpenbox = PaletteThemeName(selKitty)
+	}
 	return
 }
 
+// PaletteThemeName is the Openbox theme generated from a kitty theme.
+func PaletteThemeName(kitty string) string {
+	return kitty + "-palette"
+}
+
+func styleKeywords(style string) []string {
+	keywords := styleApplyKeywords[style]
+	if len(keywords) == 0 {
+		keywords = []string{strings.ToLower(style)}
+	}
+	return keywords
+}
+
+// StyleMatches returns every item BestMatch would consider for style, i.e.
+// everything with a positive score, in the original order.
+func StyleMatches(style string, items []string) []string {
+	keywords := styleKeywords(style)
+	var out []string
+	for _, item := range items {
+		if matchScore(item, keywords) > 0 {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 func BestMatch(items []string, keywords []string) string {
 	best := ""
 	bestScore := 0
 	for _, item := range items {
-		itemLower := strings.ToLower(item)
-		score := 0
-		for _, kw := range keywords {
-			kwLower := strings.ToLower(kw)
-			parts := splitParts(kwLower)
-			switch {
-			case itemLower == kwLower:
-				score += 1000
-			case strings.HasPrefix(itemLower, kwLower):
-				score += 500
-			case strings.Contains(itemLower, kwLower):
-				score += 300
-			default:
-				all := true
-				for _, p := range parts {
-					if p != "" && !strings.Contains(itemLower, p) {
-						all = false
-						break
-					}
-				}
-				if all {
-					score += 200 * len(parts)
-				} else {
-					for _, p := range parts {
-						if p != "" && strings.Contains(itemLower, p) {
-							score += 50
-						}
-					}
-				}
-			}
-		}
-
-		if len(itemLower) > 30 {
-			score -= (len(itemLower) - 30) * 2
-		}
-
+		score := matchScore(item, keywords)
 		if score > bestScore {
 			bestScore = score
 			best = item
@@ -122,6 +117,45 @@ func BestMatch(items []string, keywords []string) string {
 	return ""
 }
 
+func matchScore(item string, keywords []string) int {
+	itemLower := strings.ToLower(item)
+	score := 0
+	for _, kw := range keywords {
+		kwLower := strings.ToLower(kw)
+		parts := splitParts(kwLower)
+		switch {
+		case itemLower == kwLower:
+			score += 1000
+		case strings.HasPrefix(itemLower, kwLower):
+			score += 500
+		case strings.Contains(itemLower, kwLower):
+			score += 300
+		default:
+			all := true
+			for _, p := range parts {
+				if p != "" && !strings.Contains(itemLower, p) {
+					all = false
+					break
+				}
+			}
+			if all {
+				score += 200 * len(parts)
+			} else {
+				for _, p := range parts {
+					if p != "" && strings.Contains(itemLower, p) {
+						score += 50
+					}
+				}
+			}
+		}
+	}
+
+	if len(itemLower) > 30 {
+		score -= (len(itemLower) - 30) * 2
+	}
+	return score
+}
+
 func splitParts(s string) []string {
 	// same separators as Flutter: space, hyphen, underscore
 	seps := func(r rune) bool {
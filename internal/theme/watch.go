@@ -0,0 +1,104 @@
+package theme
+
+import (
+	"fmt"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// Watcher tells when something may have been installed or removed in the
+// directories the catalog is scanned from. inotify isn't recursive, so
+// every directory the scan looks into is watched on its own: the theme
+// and icon dirs, each theme in them with the subdirs that decide what it
+// is (see themePaths), and the wallpaper tree at every level.
+type Watcher struct {
+	fd   int
+	opts ScanOptions
+
+	mu      sync.Mutex
+	watched map[string]bool
+}
+
+const watchMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MOVED_FROM | unix.IN_MOVED_TO |
+	unix.IN_CLOSE_WRITE | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF | unix.IN_ONLYDIR
+
+func NewWatcher(opts ScanOptions) (*Watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("inotify: %w", err)
+	}
+	w := &Watcher{fd: fd, opts: opts, watched: map[string]bool{}}
+	w.Rewatch()
+	return w, nil
+}
+
+// WatchDirs are the directories a Watcher watches that exist right now.
+func WatchDirs(opts ScanOptions) []string {
+	dirs := themePaths(ThemeDirs(), "openbox-3", "gtk-3.0", "gtk-4.0")
+	// An icon theme's index.theme sits in its top directory.
+	dirs = append(dirs, themePaths(IconDirs())...)
+	dirs = append(dirs, KittyThemesDir())
+	scanWallpapers(nil, opts.Walls, func(dir string) { dirs = append(dirs, dir) })
+	return dirs
+}
+
+// Rewatch adds the directories that appeared since the last call, such as
+// a newly installed theme or a new wallpaper folder. Call it after each
+// rescan.
+func (w *Watcher) Rewatch() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, dir := range WatchDirs(w.opts) {
+		if w.watched[dir] || !exists(dir) {
+			continue
+		}
+		if _, err := unix.InotifyAddWatch(w.fd, dir, watchMask); err == nil {
+			w.watched[dir] = true
+		}
+	}
+}
+
+// Wait blocks until the next batch of changes. Removed directories drop
+// out of the watch set, so Rewatch picks them up again if they return.
+func (w *Watcher) Wait() error {
+	buf := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := unix.Read(w.fd, buf)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("inotify: %w", err)
+		}
+		if n < unix.SizeofInotifyEvent {
+			return fmt.Errorf("inotify: short read")
+		}
+		changed := false
+		for off := 0; off+unix.SizeofInotifyEvent <= n; {
+			ev := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			off += unix.SizeofInotifyEvent + int(ev.Len)
+			if ev.Mask&unix.IN_IGNORED != 0 {
+				w.forget()
+				continue
+			}
+			changed = true
+		}
+		if changed {
+			return nil
+		}
+	}
+}
+
+// forget clears the watch set after the kernel dropped a watch; Rewatch
+// re-adds the dirs that still exist, getting the same descriptors back.
+func (w *Watcher) forget() {
+	w.mu.Lock()
+	w.watched = map[string]bool{}
+	w.mu.Unlock()
+}
+
+func (w *Watcher) Close() error {
+	return unix.Close(w.fd)
+}
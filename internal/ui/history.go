@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/history"
+)
+
+// The History panel lists past applies, newest first. Enter puts an entry
+// back into the selection and applies it straight away.
+
+type historyLoadedMsg struct {
+	entries []history.Entry
+	err     error
+}
+
+func loadHistoryCmd() tea.Cmd {
+	return func() tea.Msg {
+		entries, err := history.Load()
+		return historyLoadedMsg{entries: entries, err: err}
+	}
+}
+
+func (m Model) rebuildHistoryList() Model {
+	l := m.lists[tabHistory]
+	lis := make([]list.Item, 0, len(m.history))
+	for i := len(m.history) - 1; i >= 0; i-- {
+		e := m.history[i]
+		when := e.Time.Local().Format("Jan 02 15:04")
+		it := item{title: historyTitle(e), label: when + "  " + e.Summary()}
+		if n := len(e.Report.Warnings); n > 0 {
+			it.detail = fmt.Sprintf("%d warning(s)", n)
+		}
+		lis = append(lis, it)
+	}
+	l.SetItems(lis)
+	m.lists[tabHistory] = l
+	return m
+}
+
+func (m Model) reapplyHistory() (Model, tea.Cmd) {
+	it, ok := m.lists[tabHistory].SelectedItem().(item)
+	if !ok || m.applying {
+		return m, nil
+	}
+	var e history.Entry
+	found := false
+	for i := len(m.history) - 1; i >= 0; i-- {
+		if historyTitle(m.history[i]) == it.title {
+			e, found = m.history[i], true
+			break
+		}
+	}
+	if !found {
+		// The list is stale, e.g. the file was rewritten since it loaded.
+		m.status = "That history entry is gone; reloading the history"
+		return m, loadHistoryCmd()
+	}
+	m.selected = e.Selections
+	m.style = e.Style
+	m = m.syncCursorToSelection()
+	m.applying = true
+	m.status = "Re-applying " + e.Time.Local().Format("Jan 02 15:04") + "…"
+	m, restore := m.restorePreview()
+	return m, tea.Batch(m.spinner.Tick, tea.Sequence(restore, applyCmd(m.selected, m.style, m.cfg.Options)))
+}
+
+// historyTitle is also what the filter matches, so it carries the full
+// date and the summary.
+func historyTitle(e history.Entry) string {
+	return e.Time.Local().Format("2006-01-02 15:04:05") + " " + e.Summary()
+}
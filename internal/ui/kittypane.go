@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/app"
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// The Kitty pane under the list shows the focused theme's palette and the
+// contrast checks an apply would run on the colors derived from it.
+
+// kittyPaneView is the pane as last built, so View doesn't reread the
+// theme on every frame.
+type kittyPaneView struct {
+	name string
+	opts app.Options
+	view string
+}
+
+var warnStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+
+// syncKittyPane rebuilds the pane while the Kitty panel is open and the
+// focused theme or the options differ from what it was built for.
+func (m Model) syncKittyPane() Model {
+	if m.expanded != tabKitty {
+		return m
+	}
+	name := ""
+	if it, ok := m.lists[tabKitty].SelectedItem().(item); ok {
+		name = it.title
+	}
+	if name == m.kittyPane.name && reflect.DeepEqual(m.cfg.Options, m.kittyPane.opts) {
+		return m
+	}
+	m.kittyPane = kittyPaneView{name: name, opts: m.cfg.Options}
+	if name != "" {
+		m.kittyPane.view = kittyPane(name, m.kittyColors[name], m.kittyErrors[name], m.cfg.Options)
+	}
+	return m
+}
+
+func (m Model) renderKittyPane() string {
+	return m.kittyPane.view
+}
+
+// kittyPane draws the scanned palette and problems of a theme, reading
+// the file when the catalog has no palette, and checks its contrast.
+func kittyPane(name string, palette theme.KittyPalette, errs []theme.KittyColorError, opts app.Options) string {
+	path := theme.KittyThemeFile(name)
+	if path == "" {
+		return ""
+	}
+	if palette == nil {
+		kc := theme.LoadKittyColors(path)
+		palette, errs = kc.Palette(), kc.Errors
+	}
+	var lines []string
+
+	sample := lipgloss.NewStyle()
+	if c, ok := palette["background"]; ok {
+		sample = sample.Background(lipgloss.Color(c))
+	}
+	if c, ok := palette["foreground"]; ok {
+		sample = sample.Foreground(lipgloss.Color(c))
+	}
+	var sw strings.Builder
+	sw.WriteString(sample.Render(" Aa ") + " ")
+	for i := 0; i < 16; i++ {
+		if c, ok := palette[fmt.Sprintf("color%d", i)]; ok {
+			sw.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color(c)).Render("██"))
+		} else {
+			sw.WriteString(dimStyle.Render("··"))
+		}
+	}
+	lines = append(lines, sw.String())
+	if len(errs) > 0 {
+		lines = append(lines, warnStyle.Render(fmt.Sprintf("⚠ %v", errs[0])))
+	}
+
+	checks, err := app.KittyContrast(name, opts)
+	if err != nil {
+		return strings.Join(lines, "\n")
+	}
+	var failed []app.ContrastCheck
+	for _, c := range checks {
+		if !c.OK() {
+			failed = append(failed, c)
+		}
+	}
+	if len(checks) > 0 {
+		summary := fmt.Sprintf("contrast: %d/%d pairs reach %.1f:1", len(checks)-len(failed), len(checks), checks[0].Min)
+		if len(failed) > 0 && opts.Contrast.AutoAdjust {
+			summary += ", the rest are adjusted on apply"
+		}
+		lines = append(lines, dimStyle.Render(summary))
+	}
+	const maxShown = 4
+	for i, c := range failed {
+		if i == maxShown {
+			lines = append(lines, dimStyle.Render(fmt.Sprintf("  … %d more", len(failed)-maxShown)))
+			break
+		}
+		c.Adjusted = "" // show the ratio as derived
+		lines = append(lines, warnStyle.Render("  ⚠ "+c.String()))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,238 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/app"
+)
+
+// The options editor is a sub-panel of LabWC ("e") for the rc.xml <theme>
+// settings besides the theme name. Unset rows leave rc.xml alone.
+
+type optRow struct {
+	key   string // "corner_radius", "font:MenuItem", ...
+	label string
+	hint  string // placeholder while editing
+}
+
+func labwcOptRows() []optRow {
+	rows := []optRow{
+		{"corner_radius", "Corner radius", "0-64"},
+		{"drop_shadows", "Drop shadows", ""},
+		{"keep_border", "Keep border", ""},
+		{"titlebar_layout", "Titlebar layout", "icon:iconify,max,close"},
+	}
+	for _, place := range app.LabwcFontPlaces {
+		rows = append(rows, optRow{"font:" + place, "Font " + place, "Sans 10 bold italic"})
+	}
+	return rows
+}
+
+func newOptInput() textinput.Model {
+	ti := textinput.New()
+	ti.CharLimit = 100
+	return ti
+}
+
+func (m Model) labwcOpts() app.LabwcOptions {
+	if m.selected.Labwc == nil {
+		return app.LabwcOptions{}
+	}
+	return *m.selected.Labwc
+}
+
+// optValue is a row's current value as text, "" when unset.
+func optValue(o app.LabwcOptions, key string) string {
+	switch key {
+	case "corner_radius":
+		if o.CornerRadius != nil {
+			return strconv.Itoa(*o.CornerRadius)
+		}
+	case "drop_shadows":
+		return boolOpt(o.DropShadows)
+	case "keep_border":
+		return boolOpt(o.KeepBorder)
+	case "titlebar_layout":
+		return o.TitlebarLayout
+	default:
+		if f, ok := o.Fonts[strings.TrimPrefix(key, "font:")]; ok {
+			return f.String()
+		}
+	}
+	return ""
+}
+
+func boolOpt(b *bool) string {
+	if b == nil {
+		return ""
+	}
+	if *b {
+		return "yes"
+	}
+	return "no"
+}
+
+// setOpt parses text into the option named key; "" unsets it.
+func setOpt(o app.LabwcOptions, key, text string) (app.LabwcOptions, error) {
+	text = strings.TrimSpace(text)
+	switch key {
+	case "corner_radius":
+		o.CornerRadius = nil
+		if text != "" {
+			n, err := strconv.Atoi(text)
+			if err != nil {
+				return o, fmt.Errorf("corner radius %q is not a number", text)
+			}
+			o.CornerRadius = &n
+		}
+	case "drop_shadows", "keep_border":
+		var b *bool
+		switch text {
+		case "yes", "no":
+			v := text == "yes"
+			b = &v
+		case "":
+		default:
+			return o, fmt.Errorf("%q: must be yes or no", text)
+		}
+		if key == "drop_shadows" {
+			o.DropShadows = b
+		} else {
+			o.KeepBorder = b
+		}
+	case "titlebar_layout":
+		o.TitlebarLayout = text
+	default:
+		place := strings.TrimPrefix(key, "font:")
+		fonts := make(map[string]app.LabwcFont, len(o.Fonts)+1)
+		for p, f := range o.Fonts {
+			fonts[p] = f
+		}
+		delete(fonts, place)
+		if text != "" {
+			f, err := app.ParseLabwcFont(text)
+			if err != nil {
+				return o, err
+			}
+			fonts[place] = f
+		}
+		o.Fonts = fonts
+		if len(fonts) == 0 {
+			o.Fonts = nil
+		}
+	}
+	return o, o.Validate()
+}
+
+func (m Model) setLabwcOpts(o app.LabwcOptions) Model {
+	if o.IsZero() {
+		m.selected.Labwc = nil
+	} else {
+		m.selected.Labwc = &o
+	}
+	return m
+}
+
+func (m Model) openLabwcOpts() Model {
+	o := m.labwcOpts()
+	cursor := m.optList.Index()
+	var items []list.Item
+	for _, r := range labwcOptRows() {
+		items = append(items, item{
+			title: r.key,
+			label: fmt.Sprintf("%-22s %s", r.label, emptyDash(optValue(o, r.key))),
+		})
+	}
+	m.optList.SetItems(items)
+	m.optList.Select(cursor)
+	m.optList.SetSize(m.lists[tabLabwc].Width(), m.lists[tabLabwc].Height())
+	m.editingOpts = true
+	return m
+}
+
+func (m Model) updateLabwcOpts(msg tea.KeyMsg) (Model, tea.Cmd) {
+	it, ok := m.optList.SelectedItem().(item)
+	switch msg.String() {
+	case "left", "esc", "e":
+		m.editingOpts = false
+		return m, nil
+	case "enter":
+		if !ok {
+			return m, nil
+		}
+		o := m.labwcOpts()
+		// yes/no rows cycle unset → yes → no.
+		if it.title == "drop_shadows" || it.title == "keep_border" {
+			next := map[string]string{"": "yes", "yes": "no", "no": ""}[optValue(o, it.title)]
+			o, _ = setOpt(o, it.title, next)
+			m = m.setLabwcOpts(o)
+			m.status = "LabWC " + it.title + ": " + emptyDash(next)
+			return m.openLabwcOpts(), nil
+		}
+		for _, r := range labwcOptRows() {
+			if r.key == it.title {
+				m.optInput.Prompt = r.label + ": "
+				m.optInput.Placeholder = r.hint
+			}
+		}
+		m.optKey = it.title
+		m.optInput.SetValue(optValue(o, it.title))
+		m.optInput.CursorEnd()
+		return m, m.optInput.Focus()
+	case "backspace", "delete":
+		if ok {
+			o, _ := setOpt(m.labwcOpts(), it.title, "")
+			m = m.setLabwcOpts(o)
+			m.status = "LabWC " + it.title + " unset"
+			return m.openLabwcOpts(), nil
+		}
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.optList, cmd = m.optList.Update(msg)
+	return m, cmd
+}
+
+// updateOptInput edits one value; invalid input keeps the editor open.
+func (m Model) updateOptInput(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.optKey = ""
+		m.optInput.Blur()
+		return m, nil
+	case "enter":
+		o, err := setOpt(m.labwcOpts(), m.optKey, m.optInput.Value())
+		if err != nil {
+			m.status = "Invalid: " + firstLine(err.Error())
+			return m, nil
+		}
+		m = m.setLabwcOpts(o)
+		m.status = "LabWC " + m.optKey + ": " + emptyDash(optValue(o, m.optKey))
+		m.optKey = ""
+		m.optInput.Blur()
+		return m.openLabwcOpts(), nil
+	}
+	var cmd tea.Cmd
+	m.optInput, cmd = m.optInput.Update(msg)
+	return m, cmd
+}
+
+func (m Model) labwcPanelSuffix() string {
+	var parts []string
+	if m.selected.Labwc != nil {
+		parts = append(parts, "+options")
+	}
+	if m.expanded == tabLabwc {
+		parts = append(parts, "e: options")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " " + strings.Join(parts, ", ")
+}
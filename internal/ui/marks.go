@@ -0,0 +1,265 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/config"
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// Favorites (★, sorted first), hidden items and tags, per panel. The list
+// filter understands "fav" and "tag:<name>" on top of fuzzy text.
+
+// category names the marks bucket of a panel; History has no marks.
+func category(t tab) string {
+	if int(t) < len(theme.Categories) {
+		return theme.Categories[t]
+	}
+	return "history"
+}
+
+func (m Model) rawItems(t tab) []string {
+	switch t {
+	case tabStyle:
+		return m.styles
+	case tabGtk:
+		return m.gtk
+	case tabIcons:
+		return m.icons
+	case tabLabwc:
+		return m.openbox
+	case tabKitty:
+		return m.kitty
+	case tabWall:
+		return m.walls
+	}
+	return nil
+}
+
+// visible applies hidden/favorite arrangement and the favorites-only toggle.
+func (m Model) visible(t tab, items []string) []string {
+	cat := category(t)
+	out := m.marks.Arrange(cat, items, m.showHidden)
+	if !m.favOnly {
+		return out
+	}
+	var favs []string
+	for _, it := range out {
+		if m.marks.IsFavorite(cat, it) {
+			favs = append(favs, it)
+		}
+	}
+	return favs
+}
+
+// markItem decorates a row with its star, hidden marker and tags.
+func (m Model) markItem(t tab, it item) item {
+	cat := category(t)
+	prefix := ""
+	if m.marks.IsFavorite(cat, it.title) {
+		prefix = "★ "
+	}
+	if m.marks.IsHidden(cat, it.title) {
+		prefix += "(hidden) "
+	}
+	if prefix != "" {
+		it.label = prefix + it.display()
+	}
+	if tags := m.marks.Tags(cat, it.title); len(tags) > 0 {
+		tagStr := "#" + strings.Join(tags, " #")
+		if it.detail != "" {
+			it.detail += " · " + tagStr
+		} else {
+			it.detail = tagStr
+		}
+	}
+	return it
+}
+
+// rebuildTab refills one panel's list, keeping the cursor on the same item.
+func (m Model) rebuildTab(t tab) Model {
+	var keep string
+	if it, ok := m.lists[t].SelectedItem().(item); ok {
+		keep = it.title
+	}
+	switch t {
+	case tabWall:
+		m = m.rebuildWallList()
+	case tabHistory:
+		m = m.rebuildHistoryList()
+	default:
+		items := m.visible(t, m.rawItems(t))
+		lis := make([]list.Item, 0, len(items))
+		for _, name := range items {
+			it := item{title: name}
+			if n := len(m.kittyErrors[name]); t == tabKitty && n > 0 {
+				it.detail = fmt.Sprintf("⚠ %d problem(s)", n)
+			}
+			if info := m.iconInfo[name]; t == tabIcons && info.Name != "" && !strings.EqualFold(info.Name, name) {
+				it.detail = info.Name
+			}
+			lis = append(lis, m.markItem(t, it))
+		}
+		l := m.lists[t]
+		l.SetItems(lis)
+		m.lists[t] = l
+	}
+	m.lists[t] = moveCursorTo(m.lists[t], keep)
+	return m
+}
+
+func (m Model) rebuildAll() Model {
+	for t := tabStyle; t < tabCount; t++ {
+		m = m.rebuildTab(t)
+	}
+	return m
+}
+
+func markFilter(marks config.Marks, cat string) list.FilterFunc {
+	return func(term string, targets []string) []list.Rank {
+		var words, tags []string
+		favOnly := false
+		for _, f := range strings.Fields(term) {
+			switch {
+			case strings.HasPrefix(f, "tag:") && len(f) > 4:
+				tags = append(tags, f[4:])
+			case f == "fav" || f == "★":
+				favOnly = true
+			default:
+				words = append(words, f)
+			}
+		}
+
+		var idx []int
+		var sub []string
+		for i, t := range targets {
+			if favOnly && !marks.IsFavorite(cat, t) {
+				continue
+			}
+			ok := true
+			for _, tag := range tags {
+				if !marks.HasTag(cat, t, tag) {
+					ok = false
+					break
+				}
+			}
+			if ok {
+				idx = append(idx, i)
+				sub = append(sub, t)
+			}
+		}
+
+		if len(words) == 0 {
+			ranks := make([]list.Rank, len(idx))
+			for i, orig := range idx {
+				ranks[i] = list.Rank{Index: orig}
+			}
+			return ranks
+		}
+		ranks := list.DefaultFilter(strings.Join(words, " "), sub)
+		for i := range ranks {
+			ranks[i].Index = idx[ranks[i].Index]
+		}
+		return ranks
+	}
+}
+
+func newTagInput() textinput.Model {
+	ti := textinput.New()
+	ti.Prompt = "Tags: "
+	ti.Placeholder = "dark, warm"
+	ti.CharLimit = 200
+	return ti
+}
+
+// markKey handles f/x/t/*/H inside an expanded list. ok is false for keys
+// it doesn't own.
+func (m Model) markKey(k string) (Model, tea.Cmd, bool) {
+	t := m.expanded
+	if t == tabHistory {
+		return m, nil, false
+	}
+	cat := category(t)
+	it, hasItem := m.lists[t].SelectedItem().(item)
+	if hasItem && it.group {
+		hasItem = false
+	}
+
+	switch k {
+	case "f":
+		if !hasItem {
+			return m, nil, true
+		}
+		if m.marks.ToggleFavorite(cat, it.title) {
+			m.status = "★ " + it.title
+		} else {
+			m.status = "Unstarred " + it.title
+		}
+	case "x":
+		if !hasItem {
+			return m, nil, true
+		}
+		if m.marks.ToggleHidden(cat, it.title) {
+			m.status = "Hidden " + it.title + " (H shows hidden)"
+		} else {
+			m.status = "Unhidden " + it.title
+		}
+	case "t":
+		if !hasItem {
+			return m, nil, true
+		}
+		m.tagging = true
+		m.tagTarget = it.title
+		m.tagInput.SetValue(strings.Join(m.marks.Tags(cat, it.title), ", "))
+		m.tagInput.CursorEnd()
+		return m, m.tagInput.Focus(), true
+	case "*":
+		m.favOnly = !m.favOnly
+		m.status = "Favorites only: " + onOff(m.favOnly)
+		return m.rebuildAll(), nil, true
+	case "H":
+		m.showHidden = !m.showHidden
+		m.status = "Show hidden: " + onOff(m.showHidden)
+		return m.rebuildAll(), nil, true
+	default:
+		return m, nil, false
+	}
+	if err := config.SaveMarks(m.marks); err != nil {
+		m.status = "Save marks failed: " + firstLine(err.Error())
+	}
+	return m.rebuildTab(t), nil, true
+}
+
+func (m Model) updateTagInput(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.tagging = false
+		m.tagInput.Blur()
+		return m, nil
+	case "enter":
+		m.tagging = false
+		m.tagInput.Blur()
+		tags := strings.FieldsFunc(m.tagInput.Value(), func(r rune) bool { return r == ',' || r == ' ' })
+		m.marks.SetTags(category(m.expanded), m.tagTarget, tags)
+		m.status = "Tags saved for " + m.tagTarget
+		if err := config.SaveMarks(m.marks); err != nil {
+			m.status = "Save marks failed: " + firstLine(err.Error())
+		}
+		return m.rebuildTab(m.expanded), nil
+	}
+	var cmd tea.Cmd
+	m.tagInput, cmd = m.tagInput.Update(msg)
+	return m, cmd
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
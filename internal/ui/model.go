@@ -1,16 +1,21 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 
 	"github.com/jaycee1285/labwcchanger-tui/internal/app"
+	"github.com/jaycee1285/labwcchanger-tui/internal/config"
+	"github.com/jaycee1285/labwcchanger-tui/internal/daemon"
+	"github.com/jaycee1285/labwcchanger-tui/internal/history"
 	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
 )
 
@@ -29,17 +34,32 @@ const (
 	tabLabwc
 	tabKitty
 	tabWall
+	tabHistory
 	tabCount
 )
 
-var tabNames = []string{"Style", "GTK", "Icons", "LabWC", "Kitty", "Walls"}
+var tabNames = []string{"Style", "GTK", "Icons", "LabWC", "Kitty", "Walls", "History"}
 
-type item struct{ title string }
+// item is one list row. title is the value that gets selected; label,
+// when set, is what's displayed instead. group rows are Walls folders.
+type item struct {
+	title  string
+	label  string
+	detail string
+	group  bool
+}
 
 func (i item) Title() string       { return i.title }
 func (i item) Description() string { return "" }
 func (i item) FilterValue() string { return i.title }
 
+func (i item) display() string {
+	if i.label != "" {
+		return i.label
+	}
+	return i.title
+}
+
 // Compact delegate for items inside expanded panels
 type compactDelegate struct {
 	normal  lipgloss.Style
@@ -59,26 +79,41 @@ func (d compactDelegate) Update(msg tea.Msg, m *list.Model) tea.Cmd   { return n
 
 func (d compactDelegate) Render(w io.Writer, m list.Model, index int, listItem list.Item) {
 	it, _ := listItem.(item)
+	fit := lipgloss.NewStyle().MaxWidth(m.Width())
 	if index == m.Index() {
-		line := "  ▸ " + it.title
-		fmt.Fprint(w, d.focused.Render(line))
+		line := d.focused.Render("  ▸ " + it.display())
+		if it.detail != "" {
+			line += dimStyle.Render("  " + it.detail)
+		}
+		fmt.Fprint(w, fit.Render(line))
 		return
 	}
-	line := "    " + it.title
-	fmt.Fprint(w, d.normal.Render(line))
+	line := "    " + it.display()
+	fmt.Fprint(w, fit.Render(d.normal.Render(line)))
 }
 
 type dataLoadedMsg struct {
-	openbox []string
-	gtk     []string
-	icons   []string
-	kitty   []string
-	walls   []string
-	styles  []string
-	current theme.CurrentSettings
+	openbox     []string
+	gtk         []string
+	icons       []string
+	kitty       []string
+	walls       []string
+	styles      []string
+	info        map[string]theme.ImageInfo
+	iconInfo    map[string]theme.IconThemeInfo
+	kittyColors map[string]theme.KittyPalette
+	kittyErrors map[string][]theme.KittyColorError
+	current     app.Selections
+	// scan is set when the lists come from the scan cache, or are empty,
+	// and a background scan should follow.
+	scan bool
 }
 
-type applyDoneMsg struct{ err error }
+type applyDoneMsg struct {
+	sel    app.Selections
+	report app.Report
+	err    error
+}
 
 type Model struct {
 	active    tab       // Currently focused panel (title row)
@@ -96,23 +131,76 @@ type Model struct {
 	walls   []string
 	styles  []string
 
+	wallInfo    map[string]theme.ImageInfo
+	iconInfo    map[string]theme.IconThemeInfo
+	kittyColors map[string]theme.KittyPalette
+	kittyErrors map[string][]theme.KittyColorError // broken kitty themes
+	collapsed   map[string]bool                    // Walls folders folded shut
+
+	outputs       []app.Output
+	wallOutput    string // output Enter assigns walls to ("" = all)
+	pickingOutput bool
+	outputList    list.Model
+
+	marks      config.Marks
+	showHidden bool
+	favOnly    bool
+	tagging    bool // editing tags of tagTarget
+	tagTarget  string
+	tagInput   textinput.Model
+
+	history []history.Entry
+
+	editingOpts bool   // LabWC options sub-panel open
+	optList     list.Model
+	optKey      string // option being typed into optInput
+	optInput    textinput.Model
+
+	kittyPane kittyPaneView
+
+	preview     bool // live preview mode
+	previewSnap app.PreviewSnapshot
+	previewed   map[string]bool // categories changed since previewSnap
+	previewSeq  int64
+	restoring   bool
+
+	scanEvents   <-chan tea.Msg // background scan, nil when done
+	scanning     map[string]theme.ScanProgress
+	scanErrs     []error
+	unscanned    bool // panels were empty when the scan started
+	rescanQueued bool // another scan once this one is done
+	rescanFull   bool
+	watcher      *theme.Watcher // nil without inotify
+	watchSeq     int64
+
+	cfg      config.Config
+	applied  app.Selections // what's on screen as far as we know
 	selected app.Selections
+	style    string // style last picked in the Style panel
 	status   string
 	applying bool
 	loaded   bool
 }
 
-func New() Model {
+func New(cfg config.Config) Model {
 	sp := spinner.New()
 	sp.Spinner = spinner.Line
 	m := Model{
+		cfg:      cfg,
 		active:   tabStyle,
 		expanded: -1, // Nothing expanded initially
 		inList:   false,
 		lists:    map[tab]list.Model{},
 		spinner:  sp,
 		status:   "Loading…",
+		preview:  cfg.LivePreview,
+	}
+	marks, err := config.LoadMarks()
+	if err != nil {
+		m.status = "Marks: " + firstLine(err.Error())
 	}
+	m.marks = marks
+	m.tagInput = newTagInput()
 	del := newCompactDelegate()
 
 	for t := tabStyle; t < tabCount; t++ {
@@ -123,33 +211,67 @@ func New() Model {
 		l.SetShowTitle(false)
 		l.SetShowPagination(true)
 		l.KeyMap.Quit.SetEnabled(false) // We handle quit ourselves
+		l.Filter = markFilter(m.marks, category(t))
 		m.lists[t] = l
 	}
+	m.outputList = newOutputList()
+	m.optList = newOutputList()
+	m.optInput = newOptInput()
 	return m
 }
 
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(m.spinner.Tick, loadDataCmd())
+	return tea.Batch(m.spinner.Tick, loadDataCmd(m.cfg.ScanOptions()), loadOutputsCmd(), loadHistoryCmd())
 }
 
-func loadDataCmd() tea.Cmd {
+func loadDataCmd(opts theme.ScanOptions) tea.Cmd {
 	return func() tea.Msg {
-		gtk := theme.ScanGtkThemes()
-		walls := theme.ScanWallpapers()
-		msg := dataLoadedMsg{
-			openbox: theme.ScanOpenboxThemes(),
-			gtk:     gtk,
-			icons:   theme.ScanIconThemes(),
-			kitty:   theme.ScanKittyThemes(),
-			walls:   walls,
-			styles:  theme.AvailableStyles(gtk, walls),
-			current: theme.LoadCurrentSettings(),
+		// A running daemon already has everything scanned.
+		if resp, err := daemon.Call(daemon.Request{Cmd: daemon.CmdList}); err == nil {
+			if cur, err := daemon.Call(daemon.Request{Cmd: daemon.CmdCurrent}); err == nil {
+				return newDataLoadedMsg(*resp.Catalog, *cur.Current)
+			}
+		}
+		cs := theme.LoadCurrentSettings()
+		current := app.Selections{
+			GtkTheme:     cs.GtkTheme,
+			IconTheme:    cs.IconTheme,
+			OpenboxTheme: cs.OpenboxTheme,
 		}
+		// Show the last scan right away (or empty panels without one) and
+		// fill in what changed as the scan goes.
+		cat, _ := theme.CachedCatalog()
+		msg := newDataLoadedMsg(cat, current)
+		msg.scan = true
 		return msg
 	}
 }
 
+func newDataLoadedMsg(cat theme.Catalog, current app.Selections) dataLoadedMsg {
+	return dataLoadedMsg{
+		openbox:     cat.Openbox,
+		gtk:         cat.Gtk,
+		icons:       cat.Icons,
+		kitty:       cat.Kitty,
+		walls:       cat.Walls,
+		styles:      cat.Styles,
+		info:        cat.WallInfo,
+		iconInfo:    cat.IconInfo,
+		kittyColors: cat.KittyColors,
+		kittyErrors: cat.KittyErrors,
+		current:     current,
+	}
+}
+
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	next, cmd := m.update(msg)
+	if nm, ok := next.(Model); ok {
+		next = nm.syncKittyPane()
+	}
+	return next, cmd
+}
+
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
@@ -165,69 +287,166 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case dataLoadedMsg:
 		m.openbox, m.gtk, m.icons, m.kitty, m.walls, m.styles = msg.openbox, msg.gtk, msg.icons, msg.kitty, msg.walls, msg.styles
-
-		m.selected.GtkTheme = msg.current.GtkTheme
-		m.selected.IconTheme = msg.current.IconTheme
-		m.selected.OpenboxTheme = msg.current.OpenboxTheme
-		m.status = "Ready"
+		m.wallInfo = msg.info
+		m.iconInfo = msg.iconInfo
+		m.kittyColors = msg.kittyColors
+		m.kittyErrors = msg.kittyErrors
+
+		m.selected = msg.current
+		m.applied = msg.current
+		if !strings.HasPrefix(m.status, "Marks:") {
+			m.status = "Ready"
+		}
 		m.loaded = true
 
-		m.lists[tabStyle] = rebuildList(m.lists[tabStyle], msg.styles)
-		m.lists[tabGtk] = rebuildList(m.lists[tabGtk], msg.gtk)
-		m.lists[tabIcons] = rebuildList(m.lists[tabIcons], msg.icons)
-		m.lists[tabLabwc] = rebuildList(m.lists[tabLabwc], msg.openbox)
-		m.lists[tabKitty] = rebuildList(m.lists[tabKitty], msg.kitty)
-		m.lists[tabWall] = rebuildList(m.lists[tabWall], msg.walls)
+		m = m.rebuildAll()
 		m = m.syncCursorToSelection()
+		if msg.scan {
+			m.unscanned = len(msg.openbox)+len(msg.gtk)+len(msg.icons)+len(msg.kitty)+len(msg.walls) == 0
+			return m, tea.Batch(startScanCmd(m.cfg.ScanOptions(), nil), startWatchCmd(m.cfg.ScanOptions()))
+		}
+		return m, startWatchCmd(m.cfg.ScanOptions())
+
+	case scanStartedMsg, scanProgressMsg, scanSectionMsg, scanDoneMsg:
+		return m.updateScan(msg)
+
+	case watchStartedMsg, watchEventMsg, rescanTickMsg:
+		return m.updateWatch(msg)
+
+	case outputsLoadedMsg:
+		// No outputs just means no per-output choices; not worth a status.
+		m.outputs = msg.outputs
+		if m.loaded {
+			// Size warnings depend on the outputs.
+			m = m.rebuildWallList()
+			m = m.syncCursorToSelection()
+		}
 		return m, nil
 
+	case historyLoadedMsg:
+		if msg.err != nil {
+			m.status = "History: " + firstLine(msg.err.Error())
+			return m, nil
+		}
+		m.history = msg.entries
+		return m.rebuildHistoryList(), nil
+
 	case applyDoneMsg:
 		m.applying = false
-		if msg.err != nil {
+		switch w := msg.report.Warnings; {
+		case msg.err != nil:
 			m.status = "Apply failed: " + firstLine(msg.err.Error())
-		} else {
+		case len(w) == 1:
+			m.status = "Applied with a warning: " + firstLine(w[0])
+		case len(w) > 1:
+			m.status = fmt.Sprintf("Applied with %d warnings: %s", len(w), firstLine(w[0]))
+		default:
 			m.status = "Applied successfully!"
 		}
+		if msg.err == nil {
+			// msg.sel is the whole selection; overlaying it would keep
+			// LabWC options that were reset before applying.
+			m.applied = msg.sel
+		}
+		return m, loadHistoryCmd()
+
+	case previewTickMsg:
+		return m.runPreview(msg)
+
+	case previewDoneMsg:
+		if msg.err != nil {
+			m.status = "Preview failed: " + firstLine(msg.err.Error())
+		} else {
+			m.status = "Preview " + msg.label
+		}
+		return m, nil
+
+	case restoreDoneMsg:
+		m.restoring = false
+		switch {
+		case msg.err != nil:
+			m.status = "Restore failed: " + firstLine(msg.err.Error())
+		case len(msg.report.Warnings) > 0:
+			m.status = "Restored; " + firstLine(msg.report.Warnings[0])
+		default:
+			m.status = "Restored the pre-preview setup"
+		}
 		return m, nil
 
 	case tea.KeyMsg:
 		k := msg.String()
 
+		if m.tagging {
+			return m.updateTagInput(msg)
+		}
+		if m.optKey != "" {
+			return m.updateOptInput(msg)
+		}
+		// While typing a filter every key belongs to the list.
+		if m.inList && m.expanded >= 0 && k != "ctrl+c" &&
+			m.lists[m.expanded].FilterState() == list.Filtering {
+			return m.forwardToList(msg)
+		}
+
 		// Global keys
 		switch k {
 		case "ctrl+c", "q":
-			return m, tea.Quit
+			// Don't leave a preview behind.
+			m, cmd = m.restorePreview()
+			return m, tea.Sequence(cmd, tea.Quit)
 		case "a":
 			if m.applying {
 				return m, nil
 			}
 			m.applying = true
 			m.status = "Applying…"
-			return m, tea.Batch(m.spinner.Tick, applyCmd(m.selected))
+			m, cmd = m.restorePreview()
+			return m, tea.Batch(m.spinner.Tick, tea.Sequence(cmd, applyCmd(m.selected, m.style, m.cfg.Options)))
+		case "p":
+			return m.togglePreview()
+		case "r":
+			m.status = "Scanning…"
+			return m.rescan(true)
 		}
 
 		// Navigation depends on whether we're in a list or at panel titles
+		if m.inList && m.expanded == tabWall && m.pickingOutput {
+			return m.updateOutputPicker(msg)
+		}
+		if m.inList && m.expanded == tabLabwc && m.editingOpts {
+			return m.updateLabwcOpts(msg)
+		}
 		if m.inList && m.expanded >= 0 {
+			if nm, cmd, ok := m.markKey(k); ok {
+				return nm, cmd
+			}
 			switch k {
 			case "left", "esc":
 				// Collapse and return to panel navigation
 				m.inList = false
+				if k == "esc" {
+					return m.restorePreview()
+				}
 				return m, nil
 			case "enter":
+				if m.expanded == tabHistory {
+					return m.reapplyHistory()
+				}
 				m = m.selectCurrentItem()
 				return m, nil
-			case "up", "down", "j", "k", "pgup", "pgdown", "home", "end":
-				// Forward to list
-				l := m.lists[m.expanded]
-				l, cmd = l.Update(msg)
-				m.lists[m.expanded] = l
-				return m, cmd
+			case "o":
+				if m.expanded == tabWall && len(m.outputs) > 0 {
+					return m.openOutputPicker(), nil
+				}
+				return m.forwardToList(msg)
+			case "e":
+				if m.expanded == tabLabwc {
+					return m.openLabwcOpts(), nil
+				}
+				return m.forwardToList(msg)
 			default:
-				// Forward filtering keys to list
-				l := m.lists[m.expanded]
-				l, cmd = l.Update(msg)
-				m.lists[m.expanded] = l
-				return m, cmd
+				// Navigation and filtering keys
+				return m.forwardToList(msg)
 			}
 		} else {
 			// Navigating panel titles
@@ -260,19 +479,33 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
-func rebuildList(l list.Model, items []string) list.Model {
-	lis := make([]list.Item, 0, len(items))
-	for _, it := range items {
-		lis = append(lis, item{title: it})
+// forwardToList hands a key to the expanded list and, in live preview,
+// schedules a preview when the cursor lands on a different item.
+func (m Model) forwardToList(msg tea.KeyMsg) (Model, tea.Cmd) {
+	l := m.lists[m.expanded]
+	before, _ := l.SelectedItem().(item)
+	l, cmd := l.Update(msg)
+	m.lists[m.expanded] = l
+	if after, ok := l.SelectedItem().(item); ok && after.title != before.title {
+		var pcmd tea.Cmd
+		m, pcmd = m.schedulePreview()
+		return m, tea.Batch(cmd, pcmd)
 	}
-	l.SetItems(lis)
-	return l
+	return m, cmd
 }
 
-func applyCmd(sel app.Selections) tea.Cmd {
+func applyCmd(sel app.Selections, style string, opts app.Options) tea.Cmd {
 	return func() tea.Msg {
-		err := app.Apply(sel)
-		return applyDoneMsg{err: err}
+		resp, err := daemon.Call(daemon.Request{Cmd: daemon.CmdApply, Selections: &sel, Style: style})
+		if errors.Is(err, daemon.ErrNotRunning) {
+			rep, err := history.Apply(sel, style, opts)
+			return applyDoneMsg{sel: sel, report: rep, err: err}
+		}
+		var rep app.Report
+		if err == nil && resp.Report != nil {
+			rep = *resp.Report
+		}
+		return applyDoneMsg{sel: sel, report: rep, err: err}
 	}
 }
 
@@ -285,10 +518,19 @@ func (m Model) selectCurrentItem() Model {
 	if !ok {
 		return m
 	}
+	if it.group {
+		return m.toggleWallGroup(it.title)
+	}
 
 	switch m.expanded {
 	case tabStyle:
-		ob, gtk, icon, kitty, wall := theme.ApplyStyle(it.title, m.openbox, m.gtk, m.icons, m.kitty, m.walls)
+		// Hidden items never win; favorites win ties.
+		ob, gtk, icon, kitty, wall := theme.ApplyStyle(it.title, m.cfg.PaletteOpenbox,
+			m.marks.Arrange(category(tabLabwc), m.openbox, false),
+			m.marks.Arrange(category(tabGtk), m.gtk, false),
+			m.marks.Arrange(category(tabIcons), m.icons, false),
+			m.marks.Arrange(category(tabKitty), m.kitty, false),
+			m.marks.Arrange(category(tabWall), m.walls, false))
 		if ob != "" {
 			m.selected.OpenboxTheme = ob
 		}
@@ -304,6 +546,10 @@ func (m Model) selectCurrentItem() Model {
 		if wall != "" {
 			m.selected.Wallpaper = wall
 		}
+		if o, ok := m.cfg.StyleLabwc[it.title]; ok {
+			m.selected = m.selected.Overlay(app.Selections{Labwc: &o})
+		}
+		m.style = it.title
 		m.status = fmt.Sprintf("Style applied: %s", it.title)
 		m = m.syncCursorToSelection()
 	case tabGtk:
@@ -318,9 +564,21 @@ func (m Model) selectCurrentItem() Model {
 	case tabKitty:
 		m.selected.KittyTheme = it.title
 		m.status = "Kitty: " + it.title
+		if errs := m.kittyErrors[it.title]; len(errs) > 0 {
+			m.status += fmt.Sprintf(" (⚠ %v", errs[0])
+			if len(errs) > 1 {
+				m.status += fmt.Sprintf(", +%d more", len(errs)-1)
+			}
+			m.status += ")"
+		}
 	case tabWall:
+		if m.wallOutput != "" {
+			m.selected.SetOutputWallpaper(m.wallOutput, it.title)
+			m.status = "Wallpaper (" + m.wallOutput + "): " + it.title + m.sizeWarning(it.title)
+			break
+		}
 		m.selected.Wallpaper = it.title
-		m.status = "Wallpaper: " + it.title
+		m.status = "Wallpaper: " + it.title + m.sizeWarning(it.title)
 	}
 	return m
 }
@@ -330,7 +588,7 @@ func (m Model) syncCursorToSelection() Model {
 	m.lists[tabIcons] = moveCursorTo(m.lists[tabIcons], m.selected.IconTheme)
 	m.lists[tabLabwc] = moveCursorTo(m.lists[tabLabwc], m.selected.OpenboxTheme)
 	m.lists[tabKitty] = moveCursorTo(m.lists[tabKitty], m.selected.KittyTheme)
-	m.lists[tabWall] = moveCursorTo(m.lists[tabWall], m.selected.Wallpaper)
+	m.lists[tabWall] = moveCursorTo(m.lists[tabWall], m.selected.WallpaperFor(m.wallOutput))
 	return m
 }
 
@@ -370,6 +628,7 @@ func (m Model) resizeLists() Model {
 		l.SetSize(listWidth, listHeight)
 		m.lists[t] = l
 	}
+	m.outputList = newOutputList()
 	return m
 }
 
@@ -437,10 +696,16 @@ func (m Model) View() string {
 
 	// Status line
 	status := m.status
-	if m.applying {
+	if m.applying || m.scanEvents != nil {
 		status = m.spinner.View() + " " + status
 	}
-	b.WriteString(statusStyle.Render(status))
+	if m.tagging {
+		b.WriteString(m.tagInput.View())
+	} else if m.optKey != "" {
+		b.WriteString(m.optInput.View() + "\n" + statusStyle.Render(status))
+	} else {
+		b.WriteString(statusStyle.Render(status))
+	}
 
 	// Wrap everything in a constrained box
 	content := b.String()
@@ -470,6 +735,10 @@ func (m Model) renderSelections() string {
 		value := selValueStyle.Render(emptyDash(sel.value))
 		lines = append(lines, label+value)
 	}
+	if m.selected.Labwc != nil {
+		lines = append(lines, dimStyle.Render("  LabWC options: ")+selValueStyle.Render(m.selected.Labwc.Summary()))
+	}
+	lines = append(lines, m.renderOutputSelections()...)
 
 	return strings.Join(lines, "\n")
 }
@@ -503,7 +772,16 @@ func (m Model) renderPanels() string {
 
 		// Item count
 		count := len(m.lists[t].Items())
+		if t == tabWall {
+			count = len(m.walls) // not the folder rows
+		}
 		countStr := dimStyle.Render(fmt.Sprintf(" (%d)", count))
+		if t == tabWall {
+			countStr += dimStyle.Render(m.wallPanelSuffix())
+		}
+		if t == tabLabwc {
+			countStr += dimStyle.Render(m.labwcPanelSuffix())
+		}
 
 		line := prefix + indicator + style.Render(tabNames[t]) + countStr
 		lines = append(lines, line)
@@ -511,9 +789,20 @@ func (m Model) renderPanels() string {
 		// If this panel is expanded, show its list
 		if isExpanded {
 			listView := m.lists[t].View()
+			if t == tabWall && m.pickingOutput {
+				listView = m.outputList.View()
+			}
+			if t == tabLabwc && m.editingOpts {
+				listView = m.optList.View()
+			}
 			// Indent the list
 			indented := indentLines(listView, "  ")
 			lines = append(lines, indented)
+			if t == tabKitty {
+				if pane := m.renderKittyPane(); pane != "" {
+					lines = append(lines, indentLines(pane, "  "))
+				}
+			}
 		}
 	}
 
@@ -535,6 +824,13 @@ func (m Model) renderHelp() string {
 		{"→ / Enter", "Expand panel"},
 		{"← / Esc", "Collapse panel"},
 		{"/", "Filter items"},
+		{"F X T", "Star / hide / tag item"},
+		{"* H", "Favorites only / show hidden"},
+		{"O", "Wallpaper output (Walls)"},
+		{"E", "Theme options (LabWC)"},
+		{"Enter", "Re-apply setup (History)"},
+		{"P", "Live preview (Esc restores)"},
+		{"R", "Rescan themes and wallpapers"},
 		{"A", "Apply changes"},
 		{"Q", "Quit"},
 	}
@@ -0,0 +1,136 @@
+package ui
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/app"
+)
+
+// The output picker is a sub-panel of Walls: choosing an output there makes
+// Enter in the Walls list assign to that output instead of to all of them.
+
+type outputsLoadedMsg struct {
+	outputs []app.Output
+	err     error
+}
+
+func loadOutputsCmd() tea.Cmd {
+	return func() tea.Msg {
+		outs, err := app.Outputs.Outputs()
+		return outputsLoadedMsg{outputs: outs, err: err}
+	}
+}
+
+func newOutputList() list.Model {
+	l := list.New([]list.Item{}, newCompactDelegate(), maxWidth-6, 6)
+	l.SetShowStatusBar(false)
+	l.SetFilteringEnabled(false)
+	l.SetShowHelp(false)
+	l.SetShowTitle(false)
+	l.SetShowPagination(true)
+	l.KeyMap.Quit.SetEnabled(false)
+	return l
+}
+
+// openOutputPicker lists "All outputs" followed by each output with its
+// resolution and current assignment; the list index minus one is the
+// position in m.outputs.
+func (m Model) openOutputPicker() Model {
+	items := []list.Item{item{title: "All outputs → " + emptyDash(m.selected.Wallpaper)}}
+	cursor := 0
+	for i, o := range m.outputs {
+		label := o.Name
+		if o.Width > 0 {
+			label += fmt.Sprintf(" (%dx%d)", o.Width, o.Height)
+		}
+		items = append(items, item{title: label + " → " + emptyDash(m.selected.WallpaperFor(o.Name))})
+		if o.Name == m.wallOutput {
+			cursor = i + 1
+		}
+	}
+	m.outputList.SetItems(items)
+	m.outputList.Select(cursor)
+	m.outputList.SetSize(m.lists[tabWall].Width(), m.lists[tabWall].Height())
+	m.pickingOutput = true
+	return m
+}
+
+func (m Model) updateOutputPicker(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch msg.String() {
+	case "left", "esc", "o":
+		m.pickingOutput = false
+		return m, nil
+	case "enter":
+		idx := m.outputList.Index()
+		if idx == 0 {
+			m.wallOutput = ""
+			m.status = "Walls: assigning to all outputs"
+		} else if idx-1 < len(m.outputs) {
+			m.wallOutput = m.outputs[idx-1].Name
+			m.status = "Walls: assigning to " + m.wallOutput
+		}
+		m.pickingOutput = false
+		return m, nil
+	case "backspace", "delete":
+		// Drop a per-output override so it follows the default again.
+		if idx := m.outputList.Index(); idx > 0 && idx-1 < len(m.outputs) {
+			name := m.outputs[idx-1].Name
+			m.selected.SetOutputWallpaper(name, "")
+			m.status = name + ": follows default wallpaper"
+			return m.openOutputPicker(), nil
+		}
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.outputList, cmd = m.outputList.Update(msg)
+	return m, cmd
+}
+
+func (m Model) renderOutputSelections() []string {
+	var lines []string
+	for _, o := range m.outputs {
+		if w := m.selected.Wallpapers[o.Name]; w != "" {
+			lines = append(lines, dimStyle.Render("  "+o.Name+": ")+selValueStyle.Render(w))
+		}
+	}
+	// Overrides for outputs that aren't connected right now.
+	var gone []string
+	for name := range m.selected.Wallpapers {
+		if !m.hasOutput(name) {
+			gone = append(gone, name)
+		}
+	}
+	sort.Strings(gone)
+	for _, name := range gone {
+		lines = append(lines, dimStyle.Render("  "+name+": ")+dimStyle.Render(m.selected.Wallpapers[name]+" (disconnected)"))
+	}
+	return lines
+}
+
+func (m Model) hasOutput(name string) bool {
+	for _, o := range m.outputs {
+		if o.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (m Model) wallPanelSuffix() string {
+	var parts []string
+	if m.wallOutput != "" {
+		parts = append(parts, "→ "+m.wallOutput)
+	}
+	if len(m.outputs) > 1 && m.expanded == tabWall {
+		parts = append(parts, "o: outputs")
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,130 @@
+package ui
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/app"
+)
+
+// Live preview: with it on, resting the cursor on an item in GTK, LabWC,
+// Kitty or Walls applies just that item. Esc puts back what was on screen
+// before the first preview.
+
+const previewDelay = 350 * time.Millisecond
+
+type previewTickMsg struct{ seq int64 }
+
+type previewDoneMsg struct {
+	label  string
+	report app.Report
+	err    error
+}
+
+type restoreDoneMsg struct {
+	report app.Report
+	err    error
+}
+
+var (
+	// previewMu keeps previews and restores from overlapping; latest lets
+	// a queued preview notice it has been superseded.
+	previewMu     sync.Mutex
+	latestPreview atomic.Int64
+)
+
+func previewCategory(t tab) (string, bool) {
+	switch t {
+	case tabGtk, tabLabwc, tabKitty, tabWall:
+		return category(t), true
+	}
+	return "", false
+}
+
+func (m Model) togglePreview() (Model, tea.Cmd) {
+	m.preview = !m.preview
+	if m.preview {
+		m.status = "Live preview on (Esc restores)"
+		return m.schedulePreview()
+	}
+	m.status = "Live preview off"
+	return m.restorePreview()
+}
+
+// schedulePreview starts the debounce for the item under the cursor.
+func (m Model) schedulePreview() (Model, tea.Cmd) {
+	if !m.preview || !m.inList || m.expanded < 0 {
+		return m, nil
+	}
+	if _, ok := previewCategory(m.expanded); !ok {
+		return m, nil
+	}
+	m.previewSeq++
+	seq := m.previewSeq
+	return m, tea.Tick(previewDelay, func(time.Time) tea.Msg { return previewTickMsg{seq: seq} })
+}
+
+func (m Model) runPreview(msg previewTickMsg) (Model, tea.Cmd) {
+	if msg.seq != m.previewSeq || !m.preview || m.restoring || !m.inList || m.expanded < 0 {
+		return m, nil
+	}
+	cat, ok := previewCategory(m.expanded)
+	if !ok {
+		return m, nil
+	}
+	it, ok := m.lists[m.expanded].SelectedItem().(item)
+	if !ok || it.group {
+		return m, nil
+	}
+	var sel app.Selections
+	if m.expanded == tabWall && m.wallOutput != "" {
+		sel.SetOutputWallpaper(m.wallOutput, it.title)
+	} else {
+		sel.Set(cat, it.title)
+	}
+
+	if len(m.previewed) == 0 {
+		m.previewSnap = app.CapturePreview(m.applied)
+		m.previewed = map[string]bool{}
+	}
+	m.previewed[cat] = true
+	latestPreview.Store(msg.seq)
+	opts := m.cfg.Options
+	return m, func() tea.Msg {
+		previewMu.Lock()
+		defer previewMu.Unlock()
+		if latestPreview.Load() != msg.seq {
+			return nil
+		}
+		rep, err := app.Preview(cat, sel, opts)
+		return previewDoneMsg{label: cat + ": " + it.title, report: rep, err: err}
+	}
+}
+
+// restorePreview undoes every category previewed since the snapshot.
+func (m Model) restorePreview() (Model, tea.Cmd) {
+	// Drop pending ticks and queued previews.
+	m.previewSeq++
+	latestPreview.Store(m.previewSeq)
+	if len(m.previewed) == 0 {
+		return m, nil
+	}
+	var cats []string
+	for _, c := range app.PreviewCategories {
+		if m.previewed[c] {
+			cats = append(cats, c)
+		}
+	}
+	m.previewed = nil
+	m.restoring = true
+	snap, opts := m.previewSnap, m.cfg.Options
+	return m, func() tea.Msg {
+		previewMu.Lock()
+		defer previewMu.Unlock()
+		rep, err := snap.Restore(cats, opts)
+		return restoreDoneMsg{report: rep, err: err}
+	}
+}
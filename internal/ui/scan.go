@@ -0,0 +1,229 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/theme"
+)
+
+// The catalog sections are scanned concurrently in the background; each
+// panel fills in as its section finishes.
+
+type scanStartedMsg struct{ events <-chan tea.Msg }
+
+type scanProgressMsg theme.ScanProgress
+
+type scanSectionMsg theme.ScanSection
+
+type scanDoneMsg struct{}
+
+// startScanCmd scans in the background; w, if set, then watches any
+// directories that appeared.
+func startScanCmd(opts theme.ScanOptions, w *theme.Watcher) tea.Cmd {
+	return func() tea.Msg {
+		ch := make(chan tea.Msg, 16)
+		go func() {
+			defer close(ch)
+			if w != nil {
+				defer w.Rewatch()
+			}
+			theme.ScanCatalogEach(opts,
+				func(p theme.ScanProgress) {
+					// Progress only needs to be roughly current.
+					select {
+					case ch <- scanProgressMsg(p):
+					default:
+					}
+				},
+				func(s theme.ScanSection) { ch <- scanSectionMsg(s) })
+		}()
+		return scanStartedMsg{ch}
+	}
+}
+
+func waitScanCmd(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		if msg, ok := <-events; ok {
+			return msg
+		}
+		return scanDoneMsg{}
+	}
+}
+
+func (m Model) updateScan(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case scanStartedMsg:
+		m.scanEvents = msg.events
+		m.scanErrs = nil
+		m.scanning = map[string]theme.ScanProgress{}
+		for _, name := range theme.ScanSectionNames {
+			m.scanning[name] = theme.ScanProgress{Section: name}
+		}
+	case scanProgressMsg:
+		if _, ok := m.scanning[msg.Section]; ok {
+			m.scanning[msg.Section] = theme.ScanProgress(msg)
+		}
+	case scanSectionMsg:
+		delete(m.scanning, msg.Name)
+		m.scanErrs = append(m.scanErrs, msg.Errors...)
+		if !msg.Cached {
+			m = m.applySection(theme.ScanSection(msg))
+		}
+	case scanDoneMsg:
+		m.scanEvents = nil
+		m.scanning = nil
+		m.unscanned = false
+		if m.rescanQueued {
+			m.rescanQueued = false
+			return m.rescan(m.rescanFull)
+		}
+	}
+	if m.scanStatusShown() {
+		if s := m.scanStatus(); s != "" {
+			m.status = s
+		} else {
+			m.status = "Ready"
+		}
+	}
+	if m.scanEvents == nil {
+		return m, nil
+	}
+	return m, waitScanCmd(m.scanEvents)
+}
+
+// applySection swaps in a rescanned section, keeping the cursors where the
+// user left them unless the panels started out empty.
+func (m Model) applySection(s theme.ScanSection) Model {
+	c := s.Catalog
+	var tabs []tab
+	switch s.Name {
+	case "themes":
+		m.openbox, m.gtk = c.Openbox, c.Gtk
+		tabs = []tab{tabLabwc, tabGtk, tabStyle}
+	case "icons":
+		m.icons, m.iconInfo = c.Icons, c.IconInfo
+		tabs = []tab{tabIcons}
+	case "kitty":
+		m.kitty, m.kittyColors, m.kittyErrors = c.Kitty, c.KittyColors, c.KittyErrors
+		m.kittyPane = kittyPaneView{} // the theme files may have changed
+		tabs = []tab{tabKitty}
+	case "walls":
+		m.walls, m.wallInfo = c.Walls, c.WallInfo
+		tabs = []tab{tabWall, tabStyle}
+	}
+	m.styles = theme.AvailableStyles(m.gtk, m.walls)
+	for _, t := range tabs {
+		m = m.rebuildTab(t)
+	}
+	if m.unscanned {
+		m = m.syncCursorToSelection()
+	}
+	return m
+}
+
+// scanStatusShown is whether the status line is free for scan news.
+func (m Model) scanStatusShown() bool {
+	return m.status == "Loading…" || m.status == "Ready" ||
+		strings.HasPrefix(m.status, "Scanning") || strings.HasPrefix(m.status, "Scan:")
+}
+
+// scanStatus is the progress of the sections still scanning, or the
+// directories that couldn't be read once all are done.
+func (m Model) scanStatus() string {
+	var parts []string
+	for _, name := range theme.ScanSectionNames {
+		p, ok := m.scanning[name]
+		switch {
+		case !ok:
+		case p.Total > 0:
+			parts = append(parts, fmt.Sprintf("%s %d/%d", name, p.Done, p.Total))
+		case p.Done > 0:
+			parts = append(parts, fmt.Sprintf("%s %d", name, p.Done))
+		default:
+			parts = append(parts, name)
+		}
+	}
+	if len(parts) > 0 {
+		return "Scanning " + strings.Join(parts, ", ") + "…"
+	}
+	switch n := len(m.scanErrs); {
+	case n == 1:
+		return "Scan: " + firstLine(m.scanErrs[0].Error())
+	case n > 1:
+		return fmt.Sprintf("Scan: %d unreadable dirs: %s", n, firstLine(m.scanErrs[0].Error()))
+	}
+	return ""
+}
+
+// rescan scans again, after the running scan if there is one. full skips
+// the cache, for the r key; changes seen by the watcher only need the
+// sections whose directories changed.
+func (m Model) rescan(full bool) (Model, tea.Cmd) {
+	if m.scanEvents != nil {
+		m.rescanQueued = true
+		m.rescanFull = m.rescanFull || full
+		return m, nil
+	}
+	m.rescanFull = false
+	opts := m.cfg.ScanOptions()
+	opts.Full = full
+	return m, startScanCmd(opts, m.watcher)
+}
+
+type watchStartedMsg struct {
+	w   *theme.Watcher
+	err error
+}
+
+type watchEventMsg struct{ err error }
+
+type rescanTickMsg struct{ seq int64 }
+
+// rescanDelay lets a theme finish unpacking before it is scanned.
+const rescanDelay = 500 * time.Millisecond
+
+func startWatchCmd(opts theme.ScanOptions) tea.Cmd {
+	return func() tea.Msg {
+		w, err := theme.NewWatcher(opts)
+		return watchStartedMsg{w, err}
+	}
+}
+
+func waitWatchCmd(w *theme.Watcher) tea.Cmd {
+	return func() tea.Msg {
+		return watchEventMsg{w.Wait()}
+	}
+}
+
+func (m Model) updateWatch(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case watchStartedMsg:
+		if msg.err != nil {
+			// Without inotify the r key still rescans.
+			return m, nil
+		}
+		m.watcher = msg.w
+		return m, waitWatchCmd(m.watcher)
+	case watchEventMsg:
+		if msg.err != nil {
+			m.watcher.Close()
+			m.watcher = nil
+			return m, nil
+		}
+		m.watchSeq++
+		seq := m.watchSeq
+		return m, tea.Batch(waitWatchCmd(m.watcher), tea.Tick(rescanDelay, func(time.Time) tea.Msg {
+			return rescanTickMsg{seq}
+		}))
+	case rescanTickMsg:
+		if msg.seq != m.watchSeq {
+			return m, nil // more changes came in
+		}
+		return m.rescan(false)
+	}
+	return m, nil
+}
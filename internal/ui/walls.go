@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+
+	"github.com/jaycee1285/labwcchanger-tui/internal/app"
+)
+
+// The Walls panel groups wallpapers by folder: files directly in
+// WallpaperDir first, then a header per folder that Enter collapses.
+
+func (m Model) rebuildWallList() Model {
+	l := m.lists[tabWall]
+	var top []string
+	groups := map[string][]string{}
+	for _, w := range m.visible(tabWall, m.walls) {
+		dir := filepath.Dir(w)
+		if dir == "." {
+			top = append(top, w)
+			continue
+		}
+		groups[dir] = append(groups[dir], w)
+	}
+	dirs := make([]string, 0, len(groups))
+	for d := range groups {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+
+	lis := make([]list.Item, 0, len(m.walls)+len(dirs))
+	for _, w := range top {
+		lis = append(lis, m.wallItem(w, ""))
+	}
+	for _, d := range dirs {
+		marker := "▾ "
+		if m.collapsed[d] {
+			marker = "▸ "
+		}
+		lis = append(lis, item{
+			title: d,
+			label: fmt.Sprintf("%s%s/ (%d)", marker, d, len(groups[d])),
+			group: true,
+		})
+		if m.collapsed[d] {
+			continue
+		}
+		for _, w := range groups[d] {
+			lis = append(lis, m.wallItem(w, "  "))
+		}
+	}
+	l.SetItems(lis)
+	m.lists[tabWall] = l
+	return m
+}
+
+func (m Model) wallItem(w, indent string) item {
+	it := item{title: w, label: filepath.Base(w)}
+	if info, ok := m.wallInfo[w]; ok {
+		it.detail = info.Describe()
+		if o, ok := m.largestOutput(); ok && info.SmallerThan(o.Width, o.Height) {
+			it.detail += " · ⚠ small"
+		}
+	}
+	it = m.markItem(tabWall, it)
+	it.label = indent + it.label
+	return it
+}
+
+// toggleWallGroup collapses or expands a folder, keeping the cursor on it.
+func (m Model) toggleWallGroup(dir string) Model {
+	if m.collapsed == nil {
+		m.collapsed = map[string]bool{}
+	}
+	m.collapsed[dir] = !m.collapsed[dir]
+	m = m.rebuildWallList()
+	l := m.lists[tabWall]
+	for idx, li := range l.Items() {
+		if it, ok := li.(item); ok && it.group && it.title == dir {
+			l.Select(idx)
+			break
+		}
+	}
+	m.lists[tabWall] = l
+	return m
+}
+
+// sizeWarning explains when a wallpaper would be upscaled on the output(s)
+// it's being assigned to.
+func (m Model) sizeWarning(wall string) string {
+	info, ok := m.wallInfo[wall]
+	if !ok || info.Width == 0 {
+		return ""
+	}
+	var small []string
+	for _, o := range m.outputs {
+		if m.wallOutput != "" && o.Name != m.wallOutput {
+			continue
+		}
+		if info.SmallerThan(o.Width, o.Height) {
+			small = append(small, fmt.Sprintf("%s %dx%d", o.Name, o.Width, o.Height))
+		}
+	}
+	if len(small) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (⚠ %dx%d is smaller than %s)", info.Width, info.Height, strings.Join(small, ", "))
+}
+
+func (m Model) largestOutput() (app.Output, bool) {
+	var best app.Output
+	for _, o := range m.outputs {
+		if o.Width*o.Height > best.Width*best.Height {
+			best = o
+		}
+	}
+	return best, best.Width > 0
+}
@@ -5,11 +5,25 @@ import (
 	"os"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jaycee1285/labwcchanger-tui/internal/config"
 	"github.com/jaycee1285/labwcchanger-tui/internal/ui"
 )
 
 func main() {
-	m := ui.New()
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1], os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, "labwcchanger-tui error:", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "labwcchanger-tui error:", err)
+		os.Exit(1)
+	}
+	m := ui.New(cfg)
 	p := tea.NewProgram(m, tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		fmt.Fprintln(os.Stderr, "labwcchanger-tui error:", err)